@@ -2,21 +2,45 @@ package main
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/awlsring/dynamic-ip-watcher/internal/adapters/primary/watcher"
 	cloudflare_dns_updater "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/dns_updater/cloudflare"
+	dyndns2_dns_updater "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/dns_updater/dyndns2"
+	exec_dns_updater "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/dns_updater/exec"
+	http_dns_updater "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/dns_updater/http"
+	rfc2136_dns_updater "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/dns_updater/rfc2136"
+	route53_dns_updater "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/dns_updater/route53"
+	ipapi_ip_info "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/ip_info/ip_api"
+	consensus_ip_retriever "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/ip_retriever/consensus"
+	dns_ip_retriever "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/ip_retriever/dns"
+	exec_ip_retriever "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/ip_retriever/exec"
+	gateway_ip_retriever "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/ip_retriever/gateway"
+	http_ip_retriever "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/ip_retriever/http"
+	interface_ip_retriever "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/ip_retriever/interface"
 	ipapi_ip_retriever "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/ip_retriever/ip_api"
+	stun_ip_retriever "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/ip_retriever/stun"
 	"github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/notifier/discord_webhook"
+	email_notifier "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/notifier/email"
+	slack_notifier "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/notifier/slack"
+	telegram_notifier "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/notifier/telegram"
 	local_storage "github.com/awlsring/dynamic-ip-watcher/internal/adapters/secondary/storage/local"
 	"github.com/awlsring/dynamic-ip-watcher/internal/config"
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
 	"github.com/awlsring/dynamic-ip-watcher/internal/core/service/address"
+	"github.com/awlsring/dynamic-ip-watcher/internal/pkg/command"
 	ipapi "github.com/awlsring/dynamic-ip-watcher/internal/pkg/ip-api"
 	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_config "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/cloudflare/cloudflare-go"
 	"github.com/rs/zerolog/log"
 )
@@ -27,18 +51,58 @@ func panicOnError(err error) {
 	}
 }
 
+// defaultHTTPTimeout bounds requests whose timeout is not configured, so an endpoint that stops responding cannot hang
+// a run.
+const defaultHTTPTimeout = 10 * time.Second
+
+// httpClientWithTimeout returns a client whose requests are bounded by timeout, or by defaultHTTPTimeout when zero.
+func httpClientWithTimeout(timeout time.Duration) *http.Client {
+	if timeout == 0 {
+		timeout = defaultHTTPTimeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
 func loadNotifiers(cfg *config.Config) []gateway.Notifier {
 	var notifiers []gateway.Notifier
 	for _, notifier := range cfg.Notifiers {
-		switch notifier {
-		case notifier.(config.DiscordNotifierConfig):
-			notifierConfig := notifier.(config.DiscordNotifierConfig)
+		switch notifierConfig := notifier.(type) {
+		case config.DiscordNotifierConfig:
 			notifier := discord_webhook.New(
 				notifierConfig.WebhookUrl,
 				notifierConfig.AvatarUrl,
 				notifierConfig.Username,
-				http.DefaultClient,
+				httpClientWithTimeout(notifierConfig.Timeout.Duration),
+				discord_webhook.WithPlainText(notifierConfig.PlainText),
+			)
+			notifiers = append(notifiers, notifier)
+		case config.SlackNotifierConfig:
+			notifiers = append(notifiers, loadSlackNotifier(notifierConfig))
+		case config.TelegramNotifierConfig:
+			notifier, err := telegram_notifier.New(
+				strings.TrimSpace(notifierConfig.Token),
+				notifierConfig.ChatId,
+				httpClientWithTimeout(notifierConfig.Timeout.Duration),
+				telegram_notifier.WithAPIURL(notifierConfig.ApiUrl),
+				telegram_notifier.WithMessageThreadID(notifierConfig.MessageThreadId),
+				telegram_notifier.WithParseMode(notifierConfig.ParseMode),
+				telegram_notifier.WithSilentInfo(notifierConfig.SilentInfo),
 			)
+			panicOnError(err)
+			notifiers = append(notifiers, notifier)
+		case config.EmailNotifierConfig:
+			notifier, err := email_notifier.New(
+				notifierConfig.Host,
+				notifierConfig.Port,
+				notifierConfig.From,
+				notifierConfig.To,
+				email_notifier.WithTLSMode(notifierConfig.TLSMode),
+				email_notifier.WithInsecureSkipVerify(notifierConfig.InsecureSkipVerify),
+				email_notifier.WithAuth(notifierConfig.AuthMechanism, notifierConfig.Username, strings.TrimSpace(notifierConfig.Password)),
+				email_notifier.WithSubject(notifierConfig.Subject),
+				email_notifier.WithTimeout(notifierConfig.Timeout.Duration),
+			)
+			panicOnError(err)
 			notifiers = append(notifiers, notifier)
 		default:
 			log.Warn().Msgf("Unknown notifier type: %s", notifier.GetNotifierType())
@@ -47,22 +111,282 @@ func loadNotifiers(cfg *config.Config) []gateway.Notifier {
 	return notifiers
 }
 
-func loadDnsUpdater(cfg *config.Config) gateway.DNSUpdater {
-	switch cfg.DNSRecord.Type {
-	case config.DnsRecordTypeCloudflare:
-		cloudflareClient, err := cloudflare.NewWithAPIToken(cfg.DNSRecord.APIKey)
+// loadSlackNotifier posts with the bot token when one is configured, and to the webhook otherwise.
+func loadSlackNotifier(notifierCfg config.SlackNotifierConfig) gateway.Notifier {
+	opts := []slack_notifier.Option{
+		slack_notifier.WithAPIURL(notifierCfg.ApiUrl),
+		slack_notifier.WithUsername(notifierCfg.Username),
+		slack_notifier.WithIconURL(notifierCfg.IconUrl),
+	}
+
+	client := httpClientWithTimeout(notifierCfg.Timeout.Duration)
+
+	var notifier gateway.Notifier
+	var err error
+	if notifierCfg.Token != "" {
+		notifier, err = slack_notifier.NewBot(strings.TrimSpace(notifierCfg.Token), notifierCfg.Channel, client, opts...)
+	} else {
+		notifier, err = slack_notifier.NewWebhook(strings.TrimSpace(notifierCfg.WebhookUrl), client, opts...)
+	}
+	panicOnError(err)
+	return notifier
+}
+
+func loadRoute53Client(recordCfg *config.Route53DNSRecordConfig) *route53.Client {
+	region := recordCfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	loadOpts := []func(*aws_config.LoadOptions) error{aws_config.WithRegion(region)}
+	if accessKeyID := strings.TrimSpace(recordCfg.AccessKeyID); accessKeyID != "" {
+		loadOpts = append(loadOpts, aws_config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(accessKeyID, strings.TrimSpace(recordCfg.SecretAccessKey), ""),
+		))
+	}
+
+	awsCfg, err := aws_config.LoadDefaultConfig(context.Background(), loadOpts...)
+	panicOnError(err)
+
+	return route53.NewFromConfig(awsCfg, func(o *route53.Options) {
+		if recordCfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(recordCfg.Endpoint)
+		}
+	})
+}
+
+func loadDnsUpdater(recordCfg config.DNSRecord) gateway.DNSUpdater {
+	switch recordCfg := recordCfg.(type) {
+	case *config.CloudflareDNSRecordConfig:
+		cloudflareClient, err := cloudflare.NewWithAPIToken(recordCfg.APIKey)
+		panicOnError(err)
+		return cloudflare_dns_updater.New(recordCfg.ZoneName, recordCfg.RecordName, cloudflareClient)
+	case *config.Route53DNSRecordConfig:
+		return route53_dns_updater.New(recordCfg.ZoneName, recordCfg.RecordName, loadRoute53Client(recordCfg),
+			route53_dns_updater.WithHostedZoneID(recordCfg.HostedZoneID),
+			route53_dns_updater.WithTTL(recordCfg.TTL),
+			route53_dns_updater.WithChangeTimeout(recordCfg.ChangeTimeout.Duration),
+		)
+	case *config.RFC2136DNSRecordConfig:
+		updater, err := rfc2136_dns_updater.New(recordCfg.Server, recordCfg.ZoneName, recordCfg.RecordName,
+			rfc2136_dns_updater.WithTSIG(recordCfg.TSIGKeyName, strings.TrimSpace(recordCfg.TSIGSecret), recordCfg.TSIGAlgorithm),
+			rfc2136_dns_updater.WithTransport(recordCfg.Transport),
+			rfc2136_dns_updater.WithTTL(recordCfg.TTL),
+			rfc2136_dns_updater.WithTimeout(recordCfg.Timeout.Duration),
+		)
+		panicOnError(err)
+		return updater
+	case *config.HTTPDNSRecordConfig:
+		updater, err := http_dns_updater.New(recordCfg.RecordName,
+			http_dns_updater.Request{
+				Method:  recordCfg.Method,
+				URL:     recordCfg.URL,
+				Headers: recordCfg.Headers,
+				Body:    recordCfg.Body,
+			},
+			http_dns_updater.SuccessCondition{
+				StatusCodes: recordCfg.SuccessStatusCodes,
+				BodyRegex:   recordCfg.SuccessBodyRegex,
+				JSONPath:    recordCfg.SuccessJSONPath,
+				JSONValue:   recordCfg.SuccessJSONValue,
+			},
+			httpClientWithTimeout(recordCfg.Timeout.Duration),
+		)
 		panicOnError(err)
-		return cloudflare_dns_updater.New(cfg.DNSRecord.ZoneName, cfg.DNSRecord.RecordName, cloudflareClient)
+		return updater
+	case *config.DynDNS2DNSRecordConfig:
+		updater, err := dyndns2_dns_updater.New(recordCfg.Server, recordCfg.RecordName, recordCfg.Username, strings.TrimSpace(recordCfg.Password),
+			dyndns2_dns_updater.WithUserAgent(recordCfg.UserAgent),
+			dyndns2_dns_updater.WithHTTPClient(httpClientWithTimeout(recordCfg.Timeout.Duration)),
+		)
+		panicOnError(err)
+		return updater
+	case *config.ExecDNSRecordConfig:
+		updater, err := exec_dns_updater.New(recordCfg.RecordName, recordCfg.Command, command.New(
+			command.WithTimeout(recordCfg.Timeout.Duration),
+			command.WithAllowedEnv(recordCfg.AllowedEnv),
+			command.WithEnv(recordCfg.Env),
+		))
+		panicOnError(err)
+		return updater
 	default:
-		log.Warn().Msgf("Unknown DNS updater type: %s", cfg.DNSRecord.Type)
+		log.Warn().Msgf("Unknown DNS updater type: %s", recordCfg.GetDNSRecordConfig().Type)
 		return nil
 	}
 }
 
-func loadIpRetriever(*config.Config) gateway.IPRetriever {
-	ipApiClient := ipapi.New()
-	ipRetriever := ipapi_ip_retriever.New(ipApiClient)
-	return ipRetriever
+func loadAddressFamilies(recordCfg *config.DNSRecordConfig) []inet.Family {
+	switch recordCfg.AddressFamily {
+	case config.AddressFamilyIPv6:
+		return []inet.Family{inet.IPv6}
+	case config.AddressFamilyBoth:
+		return []inet.Family{inet.IPv4, inet.IPv6}
+	default:
+		return []inet.Family{inet.IPv4}
+	}
+}
+
+func loadDuplicatePolicy(recordCfg *config.DNSRecordConfig) address.DuplicatePolicy {
+	switch recordCfg.DuplicatePolicy {
+	case config.DuplicatePolicyUpdateAll:
+		return address.DuplicatePolicyUpdateAll
+	case config.DuplicatePolicyDeleteDuplicates:
+		return address.DuplicatePolicyDeleteDuplicates
+	default:
+		return address.DuplicatePolicyFail
+	}
+}
+
+func loadDnsRecords(cfg *config.Config) []address.Record {
+	var records []address.Record
+	for _, dnsRecord := range cfg.DNSRecords {
+		dnsUpdater := loadDnsUpdater(dnsRecord)
+		if dnsUpdater == nil {
+			continue
+		}
+		recordCfg := dnsRecord.GetDNSRecordConfig()
+		records = append(records, address.Record{
+			ID: gateway.RecordID{
+				Provider: recordCfg.Type,
+				Zone:     recordCfg.ZoneName,
+				Name:     recordCfg.RecordName,
+			},
+			Updater:         dnsUpdater,
+			Families:        loadAddressFamilies(recordCfg),
+			CreateIfMissing: recordCfg.CreateIfMissing,
+			DuplicatePolicy: loadDuplicatePolicy(recordCfg),
+		})
+	}
+	return records
+}
+
+func loadIpRetriever(cfg *config.Config) gateway.IPRetriever {
+	return buildIpRetriever(cfg.IPRetriever)
+}
+
+func buildIpRetriever(retrieverCfg config.IPRetriever) gateway.IPRetriever {
+	httpClient := httpClientWithTimeout(retrieverCfg.GetIPRetrieverConfig().Timeout.Duration)
+
+	switch retrieverCfg := retrieverCfg.(type) {
+	case *config.ConsensusIPRetrieverConfig:
+		var sources []consensus_ip_retriever.Source
+		for _, sourceCfg := range retrieverCfg.Sources {
+			sources = append(sources, consensus_ip_retriever.Source{
+				Name:      sourceCfg.GetIPRetrieverConfig().Name,
+				Retriever: buildIpRetriever(sourceCfg),
+			})
+		}
+		ipRetriever, err := consensus_ip_retriever.New(sources,
+			consensus_ip_retriever.WithQuorum(retrieverCfg.Quorum),
+			consensus_ip_retriever.WithSourceTimeout(retrieverCfg.Timeout.Duration),
+		)
+		panicOnError(err)
+		return ipRetriever
+	case *config.DNSIPRetrieverConfig:
+		provider, ok := dns_ip_retriever.Providers[retrieverCfg.Provider]
+		if !ok {
+			panic("unknown DNS IP provider: " + retrieverCfg.Provider)
+		}
+		ipRetriever, err := dns_ip_retriever.New(provider,
+			dns_ip_retriever.WithServers(retrieverCfg.IPv4Servers, retrieverCfg.IPv6Servers),
+			dns_ip_retriever.WithQuery(retrieverCfg.QueryName, retrieverCfg.QueryType, retrieverCfg.QueryClass),
+			dns_ip_retriever.WithTimeout(retrieverCfg.Timeout.Duration),
+		)
+		panicOnError(err)
+		return ipRetriever
+	case *config.STUNIPRetrieverConfig:
+		return stun_ip_retriever.New(retrieverCfg.Servers,
+			stun_ip_retriever.WithTimeout(retrieverCfg.Timeout.Duration),
+			stun_ip_retriever.WithNATMappingDetection(!retrieverCfg.DisableNATDetection),
+		)
+	case *config.GatewayIPRetrieverConfig:
+		ipRetriever, err := gateway_ip_retriever.New(
+			gateway_ip_retriever.WithMethods(retrieverCfg.Methods),
+			gateway_ip_retriever.WithGatewayAddress(retrieverCfg.GatewayAddress),
+			gateway_ip_retriever.WithSSDPAddress(retrieverCfg.SSDPAddress),
+			gateway_ip_retriever.WithTimeout(retrieverCfg.Timeout.Duration),
+		)
+		panicOnError(err)
+		return ipRetriever
+	case *config.InterfaceIPRetrieverConfig:
+		prefixes, err := interface_ip_retriever.ParsePrefixes(retrieverCfg.Prefixes)
+		panicOnError(err)
+		excludePrefixes, err := interface_ip_retriever.ParsePrefixes(retrieverCfg.ExcludePrefixes)
+		panicOnError(err)
+		return interface_ip_retriever.New(retrieverCfg.Interface,
+			interface_ip_retriever.WithAllowPrivate(retrieverCfg.AllowPrivate),
+			interface_ip_retriever.WithTemporary(retrieverCfg.IncludeTemporary),
+			interface_ip_retriever.WithDeprecated(retrieverCfg.IncludeDeprecated),
+			interface_ip_retriever.WithPrefixes(prefixes, excludePrefixes),
+		)
+	case *config.HTTPIPRetrieverConfig:
+		ipRetriever, err := http_ip_retriever.New(retrieverCfg.IPv4URL, retrieverCfg.IPv6URL, httpClient,
+			http_ip_retriever.WithHeaders(retrieverCfg.Headers),
+			http_ip_retriever.WithRegex(retrieverCfg.Regex),
+			http_ip_retriever.WithJSONPath(retrieverCfg.JSONPath),
+		)
+		panicOnError(err)
+		return ipRetriever
+	case *config.ExecIPRetrieverConfig:
+		ipRetriever, err := exec_ip_retriever.New(retrieverCfg.Command, command.New(
+			command.WithTimeout(retrieverCfg.Timeout.Duration),
+			command.WithAllowedEnv(retrieverCfg.AllowedEnv),
+			command.WithEnv(retrieverCfg.Env),
+		))
+		panicOnError(err)
+		return ipRetriever
+	case *config.IPAPIIPRetrieverConfig:
+		return ipapi_ip_retriever.New(ipapi.New(
+			ipapi.WithTimeout(retrieverCfg.Timeout.Duration),
+			ipapi.WithAPIKey(strings.TrimSpace(retrieverCfg.APIKey)),
+		))
+	case *config.IPRetrieverConfig:
+		ipv4URL, ipv6URL := http_ip_retriever.IpifyIPv4URL, http_ip_retriever.IpifyIPv6URL
+		if retrieverCfg.Type == config.IPRetrieverTypeIcanhazip {
+			ipv4URL, ipv6URL = http_ip_retriever.IcanhazipIPv4URL, http_ip_retriever.IcanhazipIPv6URL
+		}
+		ipRetriever, err := http_ip_retriever.New(ipv4URL, ipv6URL, httpClient)
+		panicOnError(err)
+		return ipRetriever
+	default:
+		panic("unknown IP retriever type: " + retrieverCfg.GetIPRetrieverConfig().Type)
+	}
+}
+
+func loadIPInfoProvider(cfg *config.Config) gateway.IPInfoProvider {
+	return ipapi_ip_info.New(ipapi.New(ipapi.WithAPIKey(strings.TrimSpace(cfg.Enrichment.APIKey))))
+}
+
+func loadAddressOptions(cfg *config.Config) []address.Option {
+	var opts []address.Option
+
+	if hostname, err := os.Hostname(); err == nil {
+		opts = append(opts, address.WithHost(hostname))
+	}
+
+	if cfg.Reconciliation.Enabled {
+		opts = append(opts, address.WithReconciliation(cfg.Reconciliation.Interval.Duration))
+	}
+
+	if len(cfg.IPValidation.AllowedPrefixes) > 0 {
+		var prefixes []*net.IPNet
+		for _, cidr := range cfg.IPValidation.AllowedPrefixes {
+			_, prefix, err := net.ParseCIDR(cidr)
+			panicOnError(err)
+			prefixes = append(prefixes, prefix)
+		}
+		opts = append(opts, address.WithAllowedPrefixes(prefixes))
+	}
+
+	if len(cfg.IPValidation.AllowedASNs) > 0 || cfg.Enrichment.Enabled {
+		ipInfoProvider := loadIPInfoProvider(cfg)
+		opts = append(opts, address.WithAllowedASNs(cfg.IPValidation.AllowedASNs, ipInfoProvider))
+		if cfg.Enrichment.Enabled {
+			opts = append(opts, address.WithEnrichment(ipInfoProvider))
+		}
+	}
+
+	return opts
 }
 
 func loadStorage(cfg *config.Config) gateway.Storage {
@@ -77,43 +401,37 @@ func main() {
 	panicOnError(err)
 
 	notifiers := loadNotifiers(cfg)
-	dnsUpdater := loadDnsUpdater(cfg)
+	dnsRecords := loadDnsRecords(cfg)
 	ipRetriever := loadIpRetriever(cfg)
 	storage := loadStorage(cfg)
 
-	addressService := address.NewService(dnsUpdater, ipRetriever, notifiers, storage)
+	addressService := address.NewService(dnsRecords, ipRetriever, notifiers, storage, loadAddressOptions(cfg)...)
 
-	watcher := watcher.New(addressService)
+	watcher := watcher.New(addressService,
+		watcher.WithInterval(cfg.Watcher.Interval.Duration),
+		watcher.WithJitter(cfg.Watcher.Jitter.Duration),
+		watcher.WithTimeout(cfg.Watcher.Timeout.Duration),
+	)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
-	defer cancel()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-
-	done := make(chan error)
-	go func() {
-		err := watcher.Run(ctx)
-		if err != nil {
-			done <- err
-			return
-		}
-
-		done <- nil
-	}()
+	if cfg.Watcher.Daemon {
+		err = watcher.RunDaemon(ctx)
+	} else {
+		err = watcher.Run(ctx)
+	}
 
-	select {
-	case err := <-done:
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to complete")
-			os.Exit(1)
-		}
-		log.Info().Msg("Completed successfully")
-	case <-ctx.Done():
-		log.Warn().Msg("Timeout reached before completion, exiting...")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to complete")
+		stop()
 		os.Exit(1)
-	case <-sigs:
+	}
+
+	if ctx.Err() != nil {
 		log.Warn().Msg("Received signal, exiting...")
-		os.Exit(1)
+		return
 	}
+
+	log.Info().Msg("Completed successfully")
 }
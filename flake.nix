@@ -33,7 +33,7 @@
         pname = "dynamic-ip-watcher";
         version = "0.0.1";
         src = final.lib.cleanSource self;
-        vendorHash = "sha256-/xfMDF5sL7U3f+WLT+xE31WtIHdL3VZmL977DFY7PPM=";
+        vendorHash = "sha256-zdHgJGoxl9W2pt+ME3iLSn5FL/gu9VsBDRum9ooYl/s=";
         ldflags = ["-s" "-w" "-X main.version=v${version}"];
         outputName = "dynamic-ip-watcher";
       };
@@ -0,0 +1,27 @@
+package watcher
+
+import "time"
+
+type Option func(*Watcher)
+
+func WithInterval(interval time.Duration) Option {
+	return func(w *Watcher) {
+		if interval > 0 {
+			w.interval = interval
+		}
+	}
+}
+
+func WithJitter(jitter time.Duration) Option {
+	return func(w *Watcher) {
+		w.jitter = jitter
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(w *Watcher) {
+		if timeout > 0 {
+			w.timeout = timeout
+		}
+	}
+}
@@ -2,20 +2,87 @@ package watcher
 
 import (
 	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
 
 	"github.com/awlsring/dynamic-ip-watcher/internal/ports/service"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultInterval = 5 * time.Minute
+	DefaultTimeout  = 1 * time.Minute
 )
 
 type Watcher struct {
 	addressService service.Address
+	interval       time.Duration
+	jitter         time.Duration
+	timeout        time.Duration
 }
 
-func New(addressService service.Address) *Watcher {
-	return &Watcher{
+func New(addressService service.Address, opts ...Option) *Watcher {
+	watcher := &Watcher{
 		addressService: addressService,
+		interval:       DefaultInterval,
+		timeout:        DefaultTimeout,
 	}
+
+	for _, opt := range opts {
+		opt(watcher)
+	}
+
+	return watcher
 }
 
-func (w *Watcher) Run(ctx context.Context) error {
+// Run performs a single address check, bounded by the configured timeout.
+func (w *Watcher) Run(ctx context.Context) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("address check panicked: %v", r)
+		}
+	}()
+
 	return w.addressService.DetectAndHandleAddressChange(ctx)
 }
+
+// RunDaemon performs an address check immediately and then once per interval until the context is cancelled.
+// A failed check is logged and retried on the next tick rather than stopping the loop.
+func (w *Watcher) RunDaemon(ctx context.Context) error {
+	log.Info().Dur("interval", w.interval).Dur("jitter", w.jitter).Dur("timeout", w.timeout).Msg("Starting watcher in daemon mode")
+
+	for {
+		err := w.Run(ctx)
+		if ctx.Err() != nil {
+			log.Info().Msg("Watcher stopped")
+			return nil
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Address check failed, will retry on next tick")
+		}
+
+		delay := w.nextDelay()
+		log.Debug().Dur("delay", delay).Msg("Waiting for next address check")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Info().Msg("Watcher stopped")
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+func (w *Watcher) nextDelay() time.Duration {
+	if w.jitter <= 0 {
+		return w.interval
+	}
+
+	return w.interval + rand.N(w.jitter)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
 	"github.com/awlsring/dynamic-ip-watcher/internal/pkg/interfaces"
 	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
 	"github.com/cloudflare/cloudflare-go"
@@ -11,7 +12,6 @@ import (
 )
 
 const (
-	A             = "A"
 	RecordComment = "dynamic-ip-watcher"
 )
 
@@ -22,6 +22,8 @@ type CloudflareDNSUpdater struct {
 	client   interfaces.CloudflareAPI
 }
 
+var _ gateway.DuplicateRecordResolver = &CloudflareDNSUpdater{}
+
 func New(zoneName, dnsName string, client interfaces.CloudflareAPI) gateway.DNSUpdater {
 	return &CloudflareDNSUpdater{
 		zoneName: zoneName,
@@ -34,8 +36,8 @@ func (a *CloudflareDNSUpdater) RecordName() string {
 	return a.dnsName
 }
 
-func (a *CloudflareDNSUpdater) GetRecordIpAddress(ctx context.Context) (net.IP, error) {
-	record, err := a.describeRecord(ctx)
+func (a *CloudflareDNSUpdater) GetRecordIpAddress(ctx context.Context, family inet.Family) (net.IP, error) {
+	record, err := a.describeRecord(ctx, family)
 	if err != nil {
 		return nil, err
 	}
@@ -44,13 +46,18 @@ func (a *CloudflareDNSUpdater) GetRecordIpAddress(ctx context.Context) (net.IP,
 }
 
 func (a *CloudflareDNSUpdater) CreateRecordWithIpAddress(ctx context.Context, ip net.IP) error {
+	family, err := inet.FamilyOf(ip)
+	if err != nil {
+		return err
+	}
+
 	zoneId, err := a.getZoneId(ctx)
 	if err != nil {
 		return err
 	}
 
-	_, err = a.client.CreateDNSRecord(context.Background(), &cloudflare.ResourceContainer{Identifier: zoneId}, cloudflare.CreateDNSRecordParams{
-		Type:    A,
+	_, err = a.client.CreateDNSRecord(ctx, &cloudflare.ResourceContainer{Identifier: zoneId}, cloudflare.CreateDNSRecordParams{
+		Type:    family.RecordType(),
 		Name:    a.dnsName,
 		Content: ip.String(),
 		Comment: RecordComment,
@@ -59,18 +66,23 @@ func (a *CloudflareDNSUpdater) CreateRecordWithIpAddress(ctx context.Context, ip
 	return err
 }
 
-func (a *CloudflareDNSUpdater) UpdateRecordIpAddress(ctx context.Context, ip net.IP) error {
+func (a *CloudflareDNSUpdater) UpdateRecordIpAddress(ctx context.Context, ip, previousIP net.IP) error {
+	family, err := inet.FamilyOf(ip)
+	if err != nil {
+		return err
+	}
+
 	zoneId, err := a.getZoneId(ctx)
 	if err != nil {
 		return err
 	}
 
-	cloudflareRecord, err := a.describeRecord(ctx)
+	cloudflareRecord, err := a.describeRecord(ctx, family)
 	if err != nil {
 		return err
 	}
 
-	_, err = a.client.UpdateDNSRecord(context.Background(), &cloudflare.ResourceContainer{Identifier: zoneId}, cloudflare.UpdateDNSRecordParams{
+	_, err = a.client.UpdateDNSRecord(ctx, &cloudflare.ResourceContainer{Identifier: zoneId}, cloudflare.UpdateDNSRecordParams{
 		ID:      cloudflareRecord.ID,
 		Content: ip.String(),
 	})
@@ -78,6 +90,62 @@ func (a *CloudflareDNSUpdater) UpdateRecordIpAddress(ctx context.Context, ip net
 	return err
 }
 
+func (a *CloudflareDNSUpdater) UpdateAllRecordsIpAddress(ctx context.Context, ip net.IP) error {
+	family, err := inet.FamilyOf(ip)
+	if err != nil {
+		return err
+	}
+
+	zoneId, err := a.getZoneId(ctx)
+	if err != nil {
+		return err
+	}
+
+	records, err := a.listRecords(ctx, family)
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		return gateway.ErrRecordNotFound
+	}
+
+	for _, record := range records {
+		_, err = a.client.UpdateDNSRecord(ctx, &cloudflare.ResourceContainer{Identifier: zoneId}, cloudflare.UpdateDNSRecordParams{
+			ID:      record.ID,
+			Content: ip.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteDuplicateRecords keeps the first record returned by Cloudflare and deletes the rest.
+func (a *CloudflareDNSUpdater) DeleteDuplicateRecords(ctx context.Context, family inet.Family) error {
+	zoneId, err := a.getZoneId(ctx)
+	if err != nil {
+		return err
+	}
+
+	records, err := a.listRecords(ctx, family)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records[min(1, len(records)):] {
+		log.Info().Str("RecordID", record.ID).Str("Content", record.Content).Msg("Deleting duplicate DNS record")
+		err = a.client.DeleteDNSRecord(ctx, &cloudflare.ResourceContainer{Identifier: zoneId}, record.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (a *CloudflareDNSUpdater) getZoneId(context.Context) (string, error) {
 	if a.zoneId == "" {
 		zoneId, err := a.client.ZoneIDByName(a.zoneName)
@@ -91,16 +159,22 @@ func (a *CloudflareDNSUpdater) getZoneId(context.Context) (string, error) {
 	return a.zoneId, nil
 }
 
-func (a *CloudflareDNSUpdater) describeRecord(ctx context.Context) (cloudflare.DNSRecord, error) {
-	zoneId, err := a.client.ZoneIDByName(a.zoneName)
+func (a *CloudflareDNSUpdater) listRecords(ctx context.Context, family inet.Family) ([]cloudflare.DNSRecord, error) {
+	zoneId, err := a.getZoneId(ctx)
 	if err != nil {
-		return cloudflare.DNSRecord{}, err
+		return nil, err
 	}
 
 	records, _, err := a.client.ListDNSRecords(ctx, &cloudflare.ResourceContainer{Identifier: zoneId}, cloudflare.ListDNSRecordsParams{
-		Type: A,
+		Type: family.RecordType(),
 		Name: a.dnsName,
 	})
+
+	return records, err
+}
+
+func (a *CloudflareDNSUpdater) describeRecord(ctx context.Context, family inet.Family) (cloudflare.DNSRecord, error) {
+	records, err := a.listRecords(ctx, family)
 	if err != nil {
 		return cloudflare.DNSRecord{}, err
 	}
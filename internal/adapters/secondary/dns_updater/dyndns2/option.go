@@ -0,0 +1,24 @@
+package dyndns2_dns_updater
+
+import "net/http"
+
+type Option func(*DynDNS2DNSUpdater)
+
+// WithUserAgent identifies the client to the provider. The protocol asks for the form "company-device/version",
+// and some providers block generic agents.
+func WithUserAgent(userAgent string) Option {
+	return func(u *DynDNS2DNSUpdater) {
+		if userAgent != "" {
+			u.userAgent = userAgent
+		}
+	}
+}
+
+// WithHTTPClient replaces the default client, whose requests time out after DefaultTimeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(u *DynDNS2DNSUpdater) {
+		if client != nil {
+			u.client = client
+		}
+	}
+}
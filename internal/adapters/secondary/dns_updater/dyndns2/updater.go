@@ -0,0 +1,190 @@
+package dyndns2_dns_updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	UpdatePath       = "/nic/update"
+	DefaultUserAgent = "awlsring-dynamic-ip-watcher/1.0"
+	DefaultTimeout   = 10 * time.Second
+
+	// The protocol asks clients not to retry for at least 30 minutes after a server error.
+	ServerErrorBackoff = 30 * time.Minute
+	// After a configuration error the protocol asks clients to stop until the user fixes it. Retrying hourly keeps a
+	// fixed configuration working without a restart while staying well clear of abuse limits.
+	ConfigErrorBackoff = time.Hour
+	AbuseBackoff       = 24 * time.Hour
+
+	maxResponseBytes = 4 * 1024
+)
+
+var (
+	ErrBadAuth     = errors.New("badauth: username or password rejected")
+	ErrNotDonator  = errors.New("!donator: option requires a paid account")
+	ErrNotFQDN     = errors.New("notfqdn: hostname is not a fully qualified domain name")
+	ErrNoHost      = errors.New("nohost: hostname does not exist in this account")
+	ErrNumHost     = errors.New("numhost: too many hostnames in the request")
+	ErrAbuse       = errors.New("abuse: hostname is blocked for update abuse")
+	ErrBadAgent    = errors.New("badagent: user agent or request rejected")
+	ErrDNSError    = errors.New("dnserr: provider DNS error")
+	ErrServerError = errors.New("911: provider server error")
+	ErrRateLimited = errors.New("rate limited by provider")
+)
+
+// DynDNS2DNSUpdater publishes addresses with the dyndns2 /nic/update protocol spoken by DynDNS, No-IP and most
+// routers. Error responses are returned as gateway.BackoffError so the service stops sending updates the provider
+// would count as abuse. The protocol has no way to read a record back.
+type DynDNS2DNSUpdater struct {
+	updateURL *url.URL
+	hostname  string
+	username  string
+	password  string
+	userAgent string
+	client    *http.Client
+}
+
+// New creates an updater for hostname at server, a base URL such as https://dynupdate.no-ip.com. The standard
+// update path is used unless server includes a path.
+func New(server, hostname, username, password string, opts ...Option) (gateway.DNSUpdater, error) {
+	updateURL, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server url: %w", err)
+	}
+	if updateURL.Scheme == "" || updateURL.Host == "" {
+		return nil, fmt.Errorf("invalid server url: %s", server)
+	}
+	if updateURL.Path == "" || updateURL.Path == "/" {
+		updateURL.Path = UpdatePath
+	}
+
+	updater := &DynDNS2DNSUpdater{
+		updateURL: updateURL,
+		hostname:  hostname,
+		username:  username,
+		password:  password,
+		userAgent: DefaultUserAgent,
+		client:    &http.Client{Timeout: DefaultTimeout},
+	}
+
+	for _, opt := range opts {
+		opt(updater)
+	}
+
+	return updater, nil
+}
+
+func (u *DynDNS2DNSUpdater) RecordName() string {
+	return u.hostname
+}
+
+func (u *DynDNS2DNSUpdater) GetRecordIpAddress(ctx context.Context, family inet.Family) (net.IP, error) {
+	return nil, gateway.ErrRecordReadNotSupported
+}
+
+// CreateRecordWithIpAddress sends a normal update. Hostnames must already exist in the provider account.
+func (u *DynDNS2DNSUpdater) CreateRecordWithIpAddress(ctx context.Context, ip net.IP) error {
+	return u.UpdateRecordIpAddress(ctx, ip, nil)
+}
+
+func (u *DynDNS2DNSUpdater) UpdateRecordIpAddress(ctx context.Context, ip, previousIP net.IP) error {
+	if _, err := inet.FamilyOf(ip); err != nil {
+		return err
+	}
+
+	updateURL := *u.updateURL
+	query := updateURL.Query()
+	query.Set("hostname", u.hostname)
+	query.Set("myip", ip.String())
+	updateURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, updateURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(u.username, u.password)
+	req.Header.Set("User-Agent", u.userAgent)
+
+	log.Debug().Str("Host", updateURL.Host).Str("Hostname", u.hostname).Msg("Sending dyndns2 update")
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return err
+	}
+
+	return parseResponse(resp, string(body))
+}
+
+// parseResponse interprets the return code on the first line of the body, falling back to the HTTP status for
+// providers that only signal some errors that way.
+func parseResponse(resp *http.Response, body string) error {
+	line, _, _ := strings.Cut(strings.TrimSpace(body), "\n")
+	code, detail, _ := strings.Cut(strings.TrimSpace(line), " ")
+
+	switch code {
+	case "good":
+		log.Debug().Str("Address", detail).Msg("dyndns2 update accepted")
+		return nil
+	case "nochg":
+		log.Debug().Str("Address", detail).Msg("dyndns2 record already held the address")
+		return nil
+	case "badauth":
+		return &gateway.BackoffError{Err: ErrBadAuth, RetryAfter: ConfigErrorBackoff}
+	case "!donator":
+		return &gateway.BackoffError{Err: ErrNotDonator, RetryAfter: ConfigErrorBackoff}
+	case "notfqdn":
+		return &gateway.BackoffError{Err: ErrNotFQDN, RetryAfter: ConfigErrorBackoff}
+	case "nohost":
+		return &gateway.BackoffError{Err: ErrNoHost, RetryAfter: ConfigErrorBackoff}
+	case "numhost":
+		return &gateway.BackoffError{Err: ErrNumHost, RetryAfter: ConfigErrorBackoff}
+	case "badagent":
+		return &gateway.BackoffError{Err: ErrBadAgent, RetryAfter: ConfigErrorBackoff}
+	case "abuse":
+		return &gateway.BackoffError{Err: ErrAbuse, RetryAfter: AbuseBackoff}
+	case "dnserr":
+		return &gateway.BackoffError{Err: ErrDNSError, RetryAfter: ServerErrorBackoff}
+	case "911":
+		return &gateway.BackoffError{Err: ErrServerError, RetryAfter: ServerErrorBackoff}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return &gateway.BackoffError{Err: ErrBadAuth, RetryAfter: ConfigErrorBackoff}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &gateway.BackoffError{Err: ErrRateLimited, RetryAfter: retryAfter(resp.Header.Get("Retry-After"))}
+	case resp.StatusCode >= 500:
+		return &gateway.BackoffError{Err: fmt.Errorf("%w: status code %d", ErrServerError, resp.StatusCode), RetryAfter: ServerErrorBackoff}
+	default:
+		return fmt.Errorf("unexpected dyndns2 response with status code %d: %q", resp.StatusCode, line)
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(header string) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil && time.Until(at) > 0 {
+		return time.Until(at)
+	}
+	return ServerErrorBackoff
+}
@@ -0,0 +1,118 @@
+package exec_dns_updater
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"text/template"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	"github.com/awlsring/dynamic-ip-watcher/internal/pkg/command"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	EnvIP         = "IP"
+	EnvPreviousIP = "PREVIOUS_IP"
+	EnvRecordName = "RECORD_NAME"
+	EnvRecordType = "RECORD_TYPE"
+	EnvFamily     = "FAMILY"
+)
+
+// TemplateData is available to every argument of the command.
+type TemplateData struct {
+	IP         string
+	PreviousIP string
+	RecordName string
+	RecordType string
+	Family     string
+}
+
+// ExecDNSUpdater publishes addresses by running a command, which succeeds when it exits with status 0. The address
+// and record are passed in the IP, PREVIOUS_IP, RECORD_NAME, RECORD_TYPE and FAMILY variables, and arguments are Go
+// templates executed with TemplateData. Commands only write records, so they cannot be read back.
+type ExecDNSUpdater struct {
+	recordName string
+	args       []*template.Template
+	runner     *command.Runner
+}
+
+func New(recordName string, args []string, runner *command.Runner) (gateway.DNSUpdater, error) {
+	if len(args) == 0 || args[0] == "" {
+		return nil, command.ErrEmptyCommand
+	}
+
+	updater := &ExecDNSUpdater{
+		recordName: recordName,
+		runner:     runner,
+	}
+
+	for i, arg := range args {
+		tmpl, err := template.New(fmt.Sprintf("argument %d", i)).Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template for argument %d: %w", i, err)
+		}
+		updater.args = append(updater.args, tmpl)
+	}
+
+	return updater, nil
+}
+
+func (u *ExecDNSUpdater) RecordName() string {
+	return u.recordName
+}
+
+func (u *ExecDNSUpdater) GetRecordIpAddress(ctx context.Context, family inet.Family) (net.IP, error) {
+	return nil, gateway.ErrRecordReadNotSupported
+}
+
+// CreateRecordWithIpAddress runs the same command as an update, which is expected to create the record if needed.
+func (u *ExecDNSUpdater) CreateRecordWithIpAddress(ctx context.Context, ip net.IP) error {
+	return u.UpdateRecordIpAddress(ctx, ip, nil)
+}
+
+func (u *ExecDNSUpdater) UpdateRecordIpAddress(ctx context.Context, ip, previousIP net.IP) error {
+	family, err := inet.FamilyOf(ip)
+	if err != nil {
+		return err
+	}
+
+	data := TemplateData{
+		IP:         ip.String(),
+		RecordName: u.recordName,
+		RecordType: family.RecordType(),
+		Family:     family.String(),
+	}
+	if previousIP != nil {
+		data.PreviousIP = previousIP.String()
+	}
+
+	args, err := u.render(data)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Str("Command", args[0]).Str("RecordName", u.recordName).Msg("Running DNS update command")
+	_, err = u.runner.Run(ctx, args, map[string]string{
+		EnvIP:         data.IP,
+		EnvPreviousIP: data.PreviousIP,
+		EnvRecordName: data.RecordName,
+		EnvRecordType: data.RecordType,
+		EnvFamily:     data.Family,
+	})
+	return err
+}
+
+func (u *ExecDNSUpdater) render(data TemplateData) ([]string, error) {
+	args := make([]string, 0, len(u.args))
+	for _, tmpl := range u.args {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", tmpl.Name(), err)
+		}
+		args = append(args, buf.String())
+	}
+	return args, nil
+}
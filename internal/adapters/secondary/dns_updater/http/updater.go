@@ -0,0 +1,248 @@
+package http_dns_updater
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	"github.com/awlsring/dynamic-ip-watcher/internal/pkg/jsonpath"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	maxResponseBytes = 64 * 1024
+	maxErrorBodyLen  = 256
+)
+
+// Request describes the HTTP request sent to publish an address. URL, Body and header values are Go templates
+// executed with TemplateData.
+type Request struct {
+	Method  string
+	URL     string
+	Headers map[string]string
+	Body    string
+}
+
+// SuccessCondition decides whether the provider accepted an update. Every condition that is set must hold. With no
+// status codes, any 2xx status is accepted. JSONPath alone requires the value to be present and truthy; with
+// JSONValue it must equal JSONValue.
+type SuccessCondition struct {
+	StatusCodes []int
+	BodyRegex   string
+	JSONPath    string
+	JSONValue   string
+}
+
+// TemplateData is available to every template of a Request.
+type TemplateData struct {
+	IP         string
+	PreviousIP string
+	RecordName string
+	RecordType string
+	Family     string
+}
+
+// HTTPDNSUpdater publishes addresses with a single templated HTTP request, as used by most dynamic DNS services.
+// These services only accept writes, so the record cannot be read back.
+type HTTPDNSUpdater struct {
+	recordName  string
+	method      string
+	url         *template.Template
+	headers     map[string]*template.Template
+	body        *template.Template
+	statusCodes []int
+	bodyRegex   *regexp.Regexp
+	jsonPath    string
+	jsonValue   string
+	client      *http.Client
+}
+
+func New(recordName string, request Request, success SuccessCondition, client *http.Client) (gateway.DNSUpdater, error) {
+	updater := &HTTPDNSUpdater{
+		recordName:  recordName,
+		method:      strings.ToUpper(request.Method),
+		headers:     map[string]*template.Template{},
+		statusCodes: success.StatusCodes,
+		jsonPath:    success.JSONPath,
+		jsonValue:   success.JSONValue,
+		client:      client,
+	}
+
+	if updater.method == "" {
+		updater.method = http.MethodGet
+	}
+
+	var err error
+	if updater.url, err = template.New("url").Parse(request.URL); err != nil {
+		return nil, fmt.Errorf("invalid url template: %w", err)
+	}
+
+	if updater.body, err = template.New("body").Parse(request.Body); err != nil {
+		return nil, fmt.Errorf("invalid body template: %w", err)
+	}
+
+	for name, value := range request.Headers {
+		if updater.headers[name], err = template.New(name).Parse(value); err != nil {
+			return nil, fmt.Errorf("invalid template for header %s: %w", name, err)
+		}
+	}
+
+	if success.BodyRegex != "" {
+		if updater.bodyRegex, err = regexp.Compile(success.BodyRegex); err != nil {
+			return nil, fmt.Errorf("invalid success body regex: %w", err)
+		}
+	}
+
+	return updater, nil
+}
+
+func (u *HTTPDNSUpdater) RecordName() string {
+	return u.recordName
+}
+
+func (u *HTTPDNSUpdater) GetRecordIpAddress(ctx context.Context, family inet.Family) (net.IP, error) {
+	return nil, gateway.ErrRecordReadNotSupported
+}
+
+// CreateRecordWithIpAddress sends the same request as an update, as these services create records on first update.
+func (u *HTTPDNSUpdater) CreateRecordWithIpAddress(ctx context.Context, ip net.IP) error {
+	return u.UpdateRecordIpAddress(ctx, ip, nil)
+}
+
+func (u *HTTPDNSUpdater) UpdateRecordIpAddress(ctx context.Context, ip, previousIP net.IP) error {
+	family, err := inet.FamilyOf(ip)
+	if err != nil {
+		return err
+	}
+
+	data := TemplateData{
+		IP:         ip.String(),
+		RecordName: u.recordName,
+		RecordType: family.RecordType(),
+		Family:     family.String(),
+	}
+	if previousIP != nil {
+		data.PreviousIP = previousIP.String()
+	}
+
+	req, err := u.buildRequest(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Str("Method", req.Method).Str("Host", req.URL.Host).Msg("Sending DNS update request")
+	resp, err := u.client.Do(req)
+	if err != nil {
+		// the url error holds the whole url, which often carries a token in its query
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("request to %s failed: %w", req.URL.Host, urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return err
+	}
+
+	return u.checkResponse(resp.StatusCode, body)
+}
+
+func (u *HTTPDNSUpdater) buildRequest(ctx context.Context, data TemplateData) (*http.Request, error) {
+	rawURL, err := execute(u.url, data)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := execute(u.body, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var bodyReader io.Reader
+	if body != "" {
+		bodyReader = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, u.method, rawURL, bodyReader)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return nil, fmt.Errorf("invalid request url: %w", urlErr.Err)
+		}
+		return nil, err
+	}
+
+	for name, tmpl := range u.headers {
+		value, err := execute(tmpl, data)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(name, value)
+	}
+
+	return req, nil
+}
+
+func (u *HTTPDNSUpdater) checkResponse(statusCode int, body []byte) error {
+	if !u.acceptedStatus(statusCode) {
+		return fmt.Errorf("update failed with status code %d: %s", statusCode, truncate(body))
+	}
+
+	if u.bodyRegex != nil && !u.bodyRegex.Match(body) {
+		return fmt.Errorf("update response did not match %q: %s", u.bodyRegex, truncate(body))
+	}
+
+	if u.jsonPath != "" {
+		value, err := jsonpath.LookupString(body, u.jsonPath)
+		if err != nil {
+			return fmt.Errorf("update response has no usable %s: %w", u.jsonPath, err)
+		}
+
+		if u.jsonValue != "" && value != u.jsonValue {
+			return fmt.Errorf("update response %s is %q, expected %q", u.jsonPath, value, u.jsonValue)
+		}
+
+		if u.jsonValue == "" && (value == "" || value == "false" || value == "0") {
+			return fmt.Errorf("update response %s is %q", u.jsonPath, value)
+		}
+	}
+
+	return nil
+}
+
+func (u *HTTPDNSUpdater) acceptedStatus(statusCode int) bool {
+	if len(u.statusCodes) == 0 {
+		return statusCode >= 200 && statusCode <= 299
+	}
+	return slices.Contains(u.statusCodes, statusCode)
+}
+
+func execute(tmpl *template.Template, data TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+func truncate(body []byte) string {
+	text := strings.TrimSpace(string(body))
+	if len(text) > maxErrorBodyLen {
+		return text[:maxErrorBodyLen] + "..."
+	}
+	return text
+}
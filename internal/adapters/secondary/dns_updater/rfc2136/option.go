@@ -0,0 +1,51 @@
+package rfc2136_dns_updater
+
+import (
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+type Option func(*RFC2136DNSUpdater)
+
+// WithTSIG signs every message with the named key. secret is base64 encoded, as in a BIND key file. An empty
+// algorithm keeps the default of hmac-sha256.
+func WithTSIG(keyName, secret, algorithm string) Option {
+	return func(u *RFC2136DNSUpdater) {
+		if keyName == "" {
+			return
+		}
+		u.tsigKeyName = dns.Fqdn(keyName)
+		u.tsigSecret = secret
+		if algorithm != "" {
+			u.tsigAlgorithm = dns.Fqdn(strings.ToLower(algorithm))
+		}
+	}
+}
+
+// WithTransport selects "udp" or "tcp". UDP responses that are truncated are retried over TCP.
+func WithTransport(transport string) Option {
+	return func(u *RFC2136DNSUpdater) {
+		if transport != "" {
+			u.transport = transport
+		}
+	}
+}
+
+// WithTTL sets the TTL of records written to the server.
+func WithTTL(ttl uint32) Option {
+	return func(u *RFC2136DNSUpdater) {
+		if ttl > 0 {
+			u.ttl = ttl
+		}
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(u *RFC2136DNSUpdater) {
+		if timeout > 0 {
+			u.timeout = timeout
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package rfc2136_dns_updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/miekg/dns"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TransportUDP = "udp"
+	TransportTCP = "tcp"
+
+	DefaultTTL           = 300
+	DefaultTimeout       = 10 * time.Second
+	DefaultTSIGAlgorithm = "hmac-sha256"
+
+	tsigFudge = 300
+)
+
+var (
+	ErrRecordExists = errors.New("record already exists")
+)
+
+// RFC2136DNSUpdater manages records on an authoritative server with DNS UPDATE messages, signed with TSIG when a
+// key is configured.
+type RFC2136DNSUpdater struct {
+	server        string
+	zoneName      string
+	dnsName       string
+	ttl           uint32
+	transport     string
+	timeout       time.Duration
+	tsigKeyName   string
+	tsigSecret    string
+	tsigAlgorithm string
+}
+
+var _ gateway.DuplicateRecordResolver = &RFC2136DNSUpdater{}
+
+// New creates an updater for dnsName in zoneName on server, given as host or host:port.
+func New(server, zoneName, dnsName string, opts ...Option) (gateway.DNSUpdater, error) {
+	updater := &RFC2136DNSUpdater{
+		server:        withDefaultPort(server),
+		zoneName:      dns.Fqdn(zoneName),
+		dnsName:       dns.Fqdn(dnsName),
+		ttl:           DefaultTTL,
+		transport:     TransportUDP,
+		timeout:       DefaultTimeout,
+		tsigAlgorithm: dns.Fqdn(DefaultTSIGAlgorithm),
+	}
+
+	for _, opt := range opts {
+		opt(updater)
+	}
+
+	if updater.transport != TransportUDP && updater.transport != TransportTCP {
+		return nil, fmt.Errorf("unknown transport: %s", updater.transport)
+	}
+
+	if updater.tsigKeyName != "" {
+		switch updater.tsigAlgorithm {
+		case dns.HmacSHA1, dns.HmacSHA224, dns.HmacSHA256, dns.HmacSHA384, dns.HmacSHA512:
+		default:
+			return nil, fmt.Errorf("unsupported TSIG algorithm: %s", strings.TrimSuffix(updater.tsigAlgorithm, "."))
+		}
+	}
+
+	return updater, nil
+}
+
+func (u *RFC2136DNSUpdater) RecordName() string {
+	return strings.TrimSuffix(u.dnsName, ".")
+}
+
+func (u *RFC2136DNSUpdater) GetRecordIpAddress(ctx context.Context, family inet.Family) (net.IP, error) {
+	records, err := u.queryRecords(ctx, family)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, gateway.ErrRecordNotFound
+	}
+
+	if len(records) > 1 {
+		return nil, gateway.ErrMultipleRecordsFound
+	}
+
+	return recordIP(records[0]), nil
+}
+
+func (u *RFC2136DNSUpdater) CreateRecordWithIpAddress(ctx context.Context, ip net.IP) error {
+	record, err := u.newRecord(ip)
+	if err != nil {
+		return err
+	}
+
+	msg := new(dns.Msg)
+	msg.SetUpdate(u.zoneName)
+	msg.RRsetNotUsed([]dns.RR{u.rrsetOf(record)})
+	msg.Insert([]dns.RR{record})
+
+	return u.update(ctx, msg)
+}
+
+func (u *RFC2136DNSUpdater) UpdateRecordIpAddress(ctx context.Context, ip, previousIP net.IP) error {
+	family, err := inet.FamilyOf(ip)
+	if err != nil {
+		return err
+	}
+
+	records, err := u.queryRecords(ctx, family)
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		return gateway.ErrRecordNotFound
+	}
+
+	if len(records) > 1 {
+		return gateway.ErrMultipleRecordsFound
+	}
+
+	return u.replaceRecords(ctx, ip)
+}
+
+// UpdateAllRecordsIpAddress replaces the record set with ip. A DNS record set cannot hold the same address twice,
+// so this leaves a single record.
+func (u *RFC2136DNSUpdater) UpdateAllRecordsIpAddress(ctx context.Context, ip net.IP) error {
+	return u.replaceRecords(ctx, ip)
+}
+
+// DeleteDuplicateRecords keeps the first record returned by the server and deletes the rest.
+func (u *RFC2136DNSUpdater) DeleteDuplicateRecords(ctx context.Context, family inet.Family) error {
+	records, err := u.queryRecords(ctx, family)
+	if err != nil {
+		return err
+	}
+
+	if len(records) < 2 {
+		return nil
+	}
+
+	log.Info().Int("Count", len(records)-1).Msg("Deleting duplicate DNS records")
+	msg := new(dns.Msg)
+	msg.SetUpdate(u.zoneName)
+	msg.Remove(records[1:])
+
+	return u.update(ctx, msg)
+}
+
+// replaceRecords atomically swaps the record set for a single record holding ip, requiring the set to exist.
+func (u *RFC2136DNSUpdater) replaceRecords(ctx context.Context, ip net.IP) error {
+	record, err := u.newRecord(ip)
+	if err != nil {
+		return err
+	}
+
+	msg := new(dns.Msg)
+	msg.SetUpdate(u.zoneName)
+	msg.RRsetUsed([]dns.RR{u.rrsetOf(record)})
+	msg.RemoveRRset([]dns.RR{u.rrsetOf(record)})
+	msg.Insert([]dns.RR{record})
+
+	return u.update(ctx, msg)
+}
+
+func (u *RFC2136DNSUpdater) update(ctx context.Context, msg *dns.Msg) error {
+	response, err := u.exchange(ctx, msg)
+	if err != nil {
+		return err
+	}
+
+	switch response.Rcode {
+	case dns.RcodeSuccess:
+		return nil
+	case dns.RcodeNXRrset:
+		return gateway.ErrRecordNotFound
+	case dns.RcodeYXRrset:
+		return ErrRecordExists
+	default:
+		return fmt.Errorf("update rejected by %s: %s", u.server, dns.RcodeToString[response.Rcode])
+	}
+}
+
+func (u *RFC2136DNSUpdater) queryRecords(ctx context.Context, family inet.Family) ([]dns.RR, error) {
+	rrType := dns.StringToType[family.RecordType()]
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(u.dnsName, rrType)
+	msg.RecursionDesired = false
+
+	response, err := u.exchange(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Rcode == dns.RcodeNameError {
+		return nil, nil
+	}
+
+	if response.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("query for %s %s failed: %s", u.dnsName, family.RecordType(), dns.RcodeToString[response.Rcode])
+	}
+
+	var records []dns.RR
+	for _, answer := range response.Answer {
+		if answer.Header().Rrtype == rrType && strings.EqualFold(answer.Header().Name, u.dnsName) {
+			records = append(records, answer)
+		}
+	}
+
+	return records, nil
+}
+
+// exchange signs msg when a TSIG key is configured and sends it, retrying over TCP when a UDP response is truncated.
+func (u *RFC2136DNSUpdater) exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	client := &dns.Client{
+		Net:     u.transport,
+		Timeout: u.timeout,
+	}
+
+	if u.tsigKeyName != "" {
+		client.TsigSecret = map[string]string{u.tsigKeyName: u.tsigSecret}
+		msg.SetTsig(u.tsigKeyName, u.tsigAlgorithm, tsigFudge, time.Now().Unix())
+	}
+
+	response, _, err := client.ExchangeContext(ctx, msg, u.server)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Truncated && client.Net == TransportUDP {
+		log.Debug().Str("Server", u.server).Msg("DNS response truncated, retrying over TCP")
+		client.Net = TransportTCP
+		if u.tsigKeyName != "" {
+			msg.SetTsig(u.tsigKeyName, u.tsigAlgorithm, tsigFudge, time.Now().Unix())
+		}
+		response, _, err = client.ExchangeContext(ctx, msg, u.server)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+func (u *RFC2136DNSUpdater) newRecord(ip net.IP) (dns.RR, error) {
+	family, err := inet.FamilyOf(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	header := dns.RR_Header{
+		Name:   u.dnsName,
+		Rrtype: dns.StringToType[family.RecordType()],
+		Class:  dns.ClassINET,
+		Ttl:    u.ttl,
+	}
+
+	if family == inet.IPv6 {
+		return &dns.AAAA{Hdr: header, AAAA: ip}, nil
+	}
+	return &dns.A{Hdr: header, A: ip.To4()}, nil
+}
+
+// rrsetOf returns an RR that identifies the record set record belongs to, for use in prerequisites and deletes.
+func (u *RFC2136DNSUpdater) rrsetOf(record dns.RR) dns.RR {
+	return &dns.ANY{Hdr: dns.RR_Header{Name: record.Header().Name, Rrtype: record.Header().Rrtype, Class: dns.ClassINET}}
+}
+
+func recordIP(record dns.RR) net.IP {
+	switch record := record.(type) {
+	case *dns.A:
+		return record.A
+	case *dns.AAAA:
+		return record.AAAA
+	default:
+		return nil
+	}
+}
+
+func withDefaultPort(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), "53")
+}
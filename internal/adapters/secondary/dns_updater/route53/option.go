@@ -0,0 +1,40 @@
+package route53_dns_updater
+
+import "time"
+
+type Option func(*Route53DNSUpdater)
+
+// WithHostedZoneID skips looking the hosted zone up by name.
+func WithHostedZoneID(zoneId string) Option {
+	return func(u *Route53DNSUpdater) {
+		u.zoneId = zoneId
+	}
+}
+
+// WithTTL sets the TTL of created records. Existing records keep their TTL.
+func WithTTL(ttl int64) Option {
+	return func(u *Route53DNSUpdater) {
+		if ttl > 0 {
+			u.ttl = ttl
+		}
+	}
+}
+
+// WithChangeTimeout waits up to timeout, capped at MaxChangeTimeout, for a change to reach INSYNC. Changes are not
+// waited for by default.
+func WithChangeTimeout(timeout time.Duration) Option {
+	return func(u *Route53DNSUpdater) {
+		if timeout > 0 {
+			u.changeTimeout = min(timeout, MaxChangeTimeout)
+		}
+	}
+}
+
+// WithPollInterval sets how often the status of a change is checked while waiting for it.
+func WithPollInterval(interval time.Duration) Option {
+	return func(u *Route53DNSUpdater) {
+		if interval > 0 {
+			u.pollInterval = interval
+		}
+	}
+}
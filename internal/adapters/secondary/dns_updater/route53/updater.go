@@ -0,0 +1,246 @@
+package route53_dns_updater
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	"github.com/awlsring/dynamic-ip-watcher/internal/pkg/interfaces"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ChangeComment       = "dynamic-ip-watcher"
+	DefaultTTL          = 300
+	DefaultPollInterval = 5 * time.Second
+	// MaxChangeTimeout caps the wait for a change to reach INSYNC well below the timeout of a check, which updates
+	// the other records as well.
+	MaxChangeTimeout = 20 * time.Second
+)
+
+type Route53DNSUpdater struct {
+	zoneName      string
+	zoneId        string
+	dnsName       string
+	ttl           int64
+	changeTimeout time.Duration
+	pollInterval  time.Duration
+	client        interfaces.Route53API
+}
+
+var _ gateway.DuplicateRecordResolver = &Route53DNSUpdater{}
+
+func New(zoneName, dnsName string, client interfaces.Route53API, opts ...Option) gateway.DNSUpdater {
+	updater := &Route53DNSUpdater{
+		zoneName:     zoneName,
+		dnsName:      dnsName,
+		ttl:          DefaultTTL,
+		pollInterval: DefaultPollInterval,
+		client:       client,
+	}
+
+	for _, opt := range opts {
+		opt(updater)
+	}
+
+	return updater
+}
+
+func (u *Route53DNSUpdater) RecordName() string {
+	return u.dnsName
+}
+
+func (u *Route53DNSUpdater) GetRecordIpAddress(ctx context.Context, family inet.Family) (net.IP, error) {
+	recordSet, err := u.describeRecordSet(ctx, family)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(recordSet.ResourceRecords) > 1 {
+		return nil, gateway.ErrMultipleRecordsFound
+	}
+
+	return net.ParseIP(aws.ToString(recordSet.ResourceRecords[0].Value)), nil
+}
+
+func (u *Route53DNSUpdater) CreateRecordWithIpAddress(ctx context.Context, ip net.IP) error {
+	family, err := inet.FamilyOf(ip)
+	if err != nil {
+		return err
+	}
+
+	return u.changeRecordSet(ctx, types.ChangeActionCreate, types.ResourceRecordSet{
+		Name:            aws.String(u.dnsName),
+		Type:            types.RRType(family.RecordType()),
+		TTL:             aws.Int64(u.ttl),
+		ResourceRecords: []types.ResourceRecord{{Value: aws.String(ip.String())}},
+	})
+}
+
+// UpdateRecordIpAddress upserts the record set with ip, keeping its TTL. A record set holding more than one address
+// is treated as duplicate records.
+func (u *Route53DNSUpdater) UpdateRecordIpAddress(ctx context.Context, ip, previousIP net.IP) error {
+	family, err := inet.FamilyOf(ip)
+	if err != nil {
+		return err
+	}
+
+	recordSet, err := u.describeRecordSet(ctx, family)
+	if err != nil {
+		return err
+	}
+
+	if len(recordSet.ResourceRecords) > 1 {
+		return gateway.ErrMultipleRecordsFound
+	}
+
+	return u.upsertRecordSet(ctx, recordSet, ip)
+}
+
+// UpdateAllRecordsIpAddress replaces every address in the record set with ip. Route 53 does not allow an address to
+// appear twice in a record set, so this leaves a single address.
+func (u *Route53DNSUpdater) UpdateAllRecordsIpAddress(ctx context.Context, ip net.IP) error {
+	family, err := inet.FamilyOf(ip)
+	if err != nil {
+		return err
+	}
+
+	recordSet, err := u.describeRecordSet(ctx, family)
+	if err != nil {
+		return err
+	}
+
+	return u.upsertRecordSet(ctx, recordSet, ip)
+}
+
+// DeleteDuplicateRecords keeps the first address in the record set and removes the rest.
+func (u *Route53DNSUpdater) DeleteDuplicateRecords(ctx context.Context, family inet.Family) error {
+	recordSet, err := u.describeRecordSet(ctx, family)
+	if err != nil {
+		return err
+	}
+
+	if len(recordSet.ResourceRecords) < 2 {
+		return nil
+	}
+
+	log.Info().Int("Count", len(recordSet.ResourceRecords)-1).Msg("Deleting duplicate addresses from Route 53 record set")
+	recordSet.ResourceRecords = recordSet.ResourceRecords[:1]
+	return u.changeRecordSet(ctx, types.ChangeActionUpsert, recordSet)
+}
+
+func (u *Route53DNSUpdater) upsertRecordSet(ctx context.Context, recordSet types.ResourceRecordSet, ip net.IP) error {
+	if recordSet.TTL == nil {
+		recordSet.TTL = aws.Int64(u.ttl)
+	}
+	recordSet.ResourceRecords = []types.ResourceRecord{{Value: aws.String(ip.String())}}
+
+	return u.changeRecordSet(ctx, types.ChangeActionUpsert, recordSet)
+}
+
+// changeRecordSet submits a single change. Route 53 applies it to its name servers within about a minute, which is
+// only waited for when a change timeout is set.
+func (u *Route53DNSUpdater) changeRecordSet(ctx context.Context, action types.ChangeAction, recordSet types.ResourceRecordSet) error {
+	zoneId, err := u.getZoneId(ctx)
+	if err != nil {
+		return err
+	}
+
+	output, err := u.client.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneId),
+		ChangeBatch: &types.ChangeBatch{
+			Comment: aws.String(ChangeComment),
+			Changes: []types.Change{{
+				Action:            action,
+				ResourceRecordSet: &recordSet,
+			}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if output.ChangeInfo == nil || output.ChangeInfo.Status == types.ChangeStatusInsync || u.changeTimeout == 0 {
+		return nil
+	}
+
+	log.Debug().Str("ChangeId", aws.ToString(output.ChangeInfo.Id)).Msg("Waiting for Route 53 change to be in sync")
+	waiter := route53.NewResourceRecordSetsChangedWaiter(u.client, func(o *route53.ResourceRecordSetsChangedWaiterOptions) {
+		o.MinDelay = u.pollInterval
+		o.MaxDelay = max(u.pollInterval, o.MaxDelay)
+	})
+	err = waiter.Wait(ctx, &route53.GetChangeInput{Id: output.ChangeInfo.Id}, u.changeTimeout)
+	if err != nil {
+		return fmt.Errorf("change %s was submitted but did not reach INSYNC: %w", aws.ToString(output.ChangeInfo.Id), err)
+	}
+
+	return nil
+}
+
+func (u *Route53DNSUpdater) getZoneId(ctx context.Context) (string, error) {
+	if u.zoneId != "" {
+		return u.zoneId, nil
+	}
+
+	output, err := u.client.ListHostedZonesByName(ctx, &route53.ListHostedZonesByNameInput{
+		DNSName: aws.String(u.zoneName),
+	})
+	if err != nil {
+		log.Error().Str("ZoneName", u.zoneName).Err(err).Msg("Failed to list hosted zones by name")
+		return "", err
+	}
+
+	// results are sorted by name starting at zoneName, so any match is at the front
+	for _, zone := range output.HostedZones {
+		if !sameName(aws.ToString(zone.Name), u.zoneName) {
+			break
+		}
+		if zone.Config != nil && zone.Config.PrivateZone {
+			continue
+		}
+		u.zoneId = strings.TrimPrefix(aws.ToString(zone.Id), "/hostedzone/")
+		return u.zoneId, nil
+	}
+
+	return "", fmt.Errorf("no public hosted zone named %s", u.zoneName)
+}
+
+func (u *Route53DNSUpdater) describeRecordSet(ctx context.Context, family inet.Family) (types.ResourceRecordSet, error) {
+	zoneId, err := u.getZoneId(ctx)
+	if err != nil {
+		return types.ResourceRecordSet{}, err
+	}
+
+	output, err := u.client.ListResourceRecordSets(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(zoneId),
+		StartRecordName: aws.String(u.dnsName),
+		StartRecordType: types.RRType(family.RecordType()),
+		MaxItems:        aws.Int32(1),
+	})
+	if err != nil {
+		return types.ResourceRecordSet{}, err
+	}
+
+	if len(output.ResourceRecordSets) == 0 {
+		return types.ResourceRecordSet{}, gateway.ErrRecordNotFound
+	}
+
+	recordSet := output.ResourceRecordSets[0]
+	if !sameName(aws.ToString(recordSet.Name), u.dnsName) || string(recordSet.Type) != family.RecordType() || len(recordSet.ResourceRecords) == 0 {
+		return types.ResourceRecordSet{}, gateway.ErrRecordNotFound
+	}
+
+	return recordSet, nil
+}
+
+// sameName compares DNS names ignoring case and the trailing dot Route 53 adds.
+func sameName(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
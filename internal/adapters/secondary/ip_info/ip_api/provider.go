@@ -0,0 +1,53 @@
+package ipapi_ip_info
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	ipapi "github.com/awlsring/dynamic-ip-watcher/internal/pkg/ip-api"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+)
+
+type IPInfoProviderIPAPI struct {
+	client ipapi.Client
+}
+
+func New(client ipapi.Client) gateway.IPInfoProvider {
+	return &IPInfoProviderIPAPI{
+		client: client,
+	}
+}
+
+func (p *IPInfoProviderIPAPI) GetIPInfo(ctx context.Context, ip net.IP) (inet.IPInfo, error) {
+	response, err := p.client.QueryIPAddress(ctx, ip.String())
+	if err != nil {
+		return inet.IPInfo{}, err
+	}
+
+	return inet.IPInfo{
+		ASN:         parseASN(response.AS),
+		ASName:      response.ASName,
+		ISP:         response.ISP,
+		Org:         response.ORG,
+		Country:     response.Country,
+		CountryCode: response.CountryCode,
+		Region:      response.RegionName,
+		City:        response.City,
+		Mobile:      response.Mobile,
+		Proxy:       response.Proxy,
+		Hosting:     response.Hosting,
+	}, nil
+}
+
+// parseASN reads the number from an AS field such as "AS15169 Google LLC", returning zero when there is none.
+func parseASN(as string) uint32 {
+	number, _, _ := strings.Cut(as, " ")
+	asn, err := strconv.ParseUint(strings.TrimPrefix(number, "AS"), 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(asn)
+}
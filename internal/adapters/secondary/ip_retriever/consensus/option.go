@@ -0,0 +1,23 @@
+package consensus_ip_retriever
+
+import "time"
+
+type Option func(*ConsensusIPRetriever)
+
+// WithQuorum sets how many sources must return the same address before it is used.
+func WithQuorum(quorum int) Option {
+	return func(r *ConsensusIPRetriever) {
+		if quorum > 0 {
+			r.quorum = quorum
+		}
+	}
+}
+
+// WithSourceTimeout limits how long each source is waited on before the next one is queried in its place.
+func WithSourceTimeout(timeout time.Duration) Option {
+	return func(r *ConsensusIPRetriever) {
+		if timeout > 0 {
+			r.sourceTimeout = timeout
+		}
+	}
+}
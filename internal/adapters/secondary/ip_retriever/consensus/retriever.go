@@ -0,0 +1,168 @@
+package consensus_ip_retriever
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/event"
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultSourceTimeout = 10 * time.Second
+)
+
+var (
+	ErrNoQuorum = errors.New("no address reached quorum")
+)
+
+// Source is an IP retriever queried for consensus, named for logs and warnings.
+type Source struct {
+	Name      string
+	Retriever gateway.IPRetriever
+}
+
+// ConsensusIPRetriever only trusts an address once a quorum of sources agree on it. The first quorum sources are
+// queried concurrently, and the next source in order is added whenever one fails, times out or disagrees, until
+// quorum is reached or every source has answered. Disagreements are returned as warnings, along with those of the
+// sources.
+type ConsensusIPRetriever struct {
+	sources       []Source
+	quorum        int
+	sourceTimeout time.Duration
+}
+
+var _ gateway.DetailedIPRetriever = &ConsensusIPRetriever{}
+
+// New creates a retriever over sources, in order of preference. The quorum defaults to a majority of the sources.
+func New(sources []Source, opts ...Option) (gateway.IPRetriever, error) {
+	retriever := &ConsensusIPRetriever{
+		sources:       sources,
+		quorum:        len(sources)/2 + 1,
+		sourceTimeout: DefaultSourceTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(retriever)
+	}
+
+	if len(sources) == 0 {
+		return nil, errors.New("at least one source is required")
+	}
+
+	if retriever.quorum > len(sources) {
+		return nil, fmt.Errorf("quorum of %d cannot be reached with %d sources", retriever.quorum, len(sources))
+	}
+
+	return retriever, nil
+}
+
+func (r *ConsensusIPRetriever) GetPublicIPv4(ctx context.Context) (net.IP, error) {
+	retrieval, err := r.RetrievePublicIP(ctx, inet.IPv4)
+	return retrieval.IP, err
+}
+
+func (r *ConsensusIPRetriever) GetPublicIPv6(ctx context.Context) (net.IP, error) {
+	retrieval, err := r.RetrievePublicIP(ctx, inet.IPv6)
+	return retrieval.IP, err
+}
+
+type answer struct {
+	source    Source
+	retrieval gateway.Retrieval
+	err       error
+}
+
+// RetrievePublicIP returns the address that reached quorum, with the NAT mapping detected by the first source agreeing
+// on it that detected one, along with the warnings of every source that answered and a warning when the sources
+// disagreed, even if none reached quorum.
+func (r *ConsensusIPRetriever) RetrievePublicIP(ctx context.Context, family inet.Family) (gateway.Retrieval, error) {
+	queryCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	answers := make(chan answer, len(r.sources))
+	next, pending := 0, 0
+	query := func() {
+		source := r.sources[next]
+		next++
+		pending++
+		go func() {
+			sourceCtx, cancel := context.WithTimeout(queryCtx, r.sourceTimeout)
+			defer cancel()
+			retrieval, err := retrieve(sourceCtx, source.Retriever, family)
+			answers <- answer{source: source, retrieval: retrieval, err: err}
+		}()
+	}
+
+	for next < r.quorum {
+		query()
+	}
+
+	var received []event.SourceAnswer
+	var warnings []event.Event
+	var errs []error
+	votes := map[string]int{}
+	natMappings := map[string]inet.NATMapping{}
+	best := 0
+	for pending > 0 {
+		a := <-answers
+		pending--
+		warnings = append(warnings, a.retrieval.Warnings...)
+
+		logger := log.With().Str("source", a.source.Name).Stringer("family", family).Logger()
+		if a.err != nil {
+			logger.Warn().Err(a.err).Msg("IP source failed")
+			errs = append(errs, fmt.Errorf("%s: %w", a.source.Name, a.err))
+		} else {
+			ip := a.retrieval.IP
+			logger.Debug().Str("ip", ip.String()).Msg("IP source answered")
+			received = append(received, event.SourceAnswer{Source: a.source.Name, IP: ip})
+			votes[ip.String()]++
+			best = max(best, votes[ip.String()])
+			if natMappings[ip.String()] == "" {
+				natMappings[ip.String()] = a.retrieval.NATMapping
+			}
+
+			if votes[ip.String()] >= r.quorum {
+				if len(votes) > 1 {
+					warnings = append(warnings, event.NewSourcesDisagreeEvent(family, received, ip))
+				}
+				return gateway.Retrieval{IP: ip, NATMapping: natMappings[ip.String()], Warnings: warnings}, nil
+			}
+		}
+
+		// fall back to further sources while the answers still pending could not reach quorum
+		for best+pending < r.quorum && next < len(r.sources) {
+			query()
+		}
+	}
+
+	if len(votes) > 1 {
+		warnings = append(warnings, event.NewSourcesDisagreeEvent(family, received, nil))
+	}
+
+	err := fmt.Errorf("%w for %s, best agreement was %d of %d", ErrNoQuorum, family, best, r.quorum)
+	if len(errs) > 0 {
+		err = fmt.Errorf("%w: %w", err, errors.Join(errs...))
+	}
+	return gateway.Retrieval{Warnings: warnings}, err
+}
+
+// retrieve queries a single source, keeping its warnings even when it fails.
+func retrieve(ctx context.Context, retriever gateway.IPRetriever, family inet.Family) (gateway.Retrieval, error) {
+	retrieval, err := gateway.RetrievePublicIP(ctx, retriever, family)
+	if err != nil {
+		return gateway.Retrieval{Warnings: retrieval.Warnings}, err
+	}
+
+	if !family.Matches(retrieval.IP) {
+		return gateway.Retrieval{Warnings: retrieval.Warnings}, fmt.Errorf("returned %s for %s: %w", retrieval.IP, family, inet.ErrInvalidAddress)
+	}
+
+	return retrieval, nil
+}
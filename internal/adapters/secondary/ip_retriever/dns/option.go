@@ -0,0 +1,43 @@
+package dns_ip_retriever
+
+import "time"
+
+type Option func(*DNSIPRetriever)
+
+// WithServers replaces the provider's resolvers for each family, given as host or host:port. Empty lists keep the
+// provider's resolvers.
+func WithServers(ipv4Servers, ipv6Servers []string) Option {
+	return func(r *DNSIPRetriever) {
+		if len(ipv4Servers) > 0 {
+			r.ipv4Servers = ipv4Servers
+		}
+		if len(ipv6Servers) > 0 {
+			r.ipv6Servers = ipv6Servers
+		}
+	}
+}
+
+// WithQuery replaces the provider's query. queryType is QueryTypeAddress or QueryTypeTXT and queryClass a class
+// such as IN or CH. Empty values keep the provider's settings.
+func WithQuery(queryName, queryType, queryClass string) Option {
+	return func(r *DNSIPRetriever) {
+		if queryName != "" {
+			r.queryName = queryName
+		}
+		if queryType != "" {
+			r.queryType = queryType
+		}
+		if queryClass != "" {
+			r.queryClass = queryClass
+		}
+	}
+}
+
+// WithTimeout limits how long each server is waited on.
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *DNSIPRetriever) {
+		if timeout > 0 {
+			r.timeout = timeout
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package dns_ip_retriever
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/miekg/dns"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// QueryTypeAddress asks for an A record when retrieving IPv4 and AAAA when retrieving IPv6.
+	QueryTypeAddress = "address"
+	QueryTypeTXT     = "TXT"
+
+	DefaultTimeout = 5 * time.Second
+)
+
+// Provider describes a resolver that answers a query with the address the query came from.
+type Provider struct {
+	QueryName   string
+	QueryType   string
+	QueryClass  string
+	IPv4Servers []string
+	IPv6Servers []string
+}
+
+var (
+	OpenDNS = Provider{
+		QueryName:   "myip.opendns.com",
+		QueryType:   QueryTypeAddress,
+		QueryClass:  "IN",
+		IPv4Servers: []string{"208.67.222.222", "208.67.220.220"},
+		IPv6Servers: []string{"2620:119:35::35", "2620:119:53::53"},
+	}
+	Cloudflare = Provider{
+		QueryName:   "whoami.cloudflare",
+		QueryType:   QueryTypeTXT,
+		QueryClass:  "CH",
+		IPv4Servers: []string{"1.1.1.1", "1.0.0.1"},
+		IPv6Servers: []string{"2606:4700:4700::1111", "2606:4700:4700::1001"},
+	}
+	Google = Provider{
+		QueryName:   "o-o.myaddr.l.google.com",
+		QueryType:   QueryTypeTXT,
+		QueryClass:  "IN",
+		IPv4Servers: []string{"216.239.32.10", "216.239.34.10"},
+		IPv6Servers: []string{"2001:4860:4802:32::a", "2001:4860:4802:34::a"},
+	}
+
+	Providers = map[string]Provider{
+		"opendns":    OpenDNS,
+		"cloudflare": Cloudflare,
+		"google":     Google,
+	}
+)
+
+var (
+	ErrNoAddressInAnswer = errors.New("no address in answer")
+)
+
+// DNSIPRetriever learns the public address from resolvers that report where a query came from. Each family is
+// queried over that family, trying the servers in order until one answers.
+type DNSIPRetriever struct {
+	queryName   string
+	queryType   string
+	queryClass  string
+	class       uint16
+	ipv4Servers []string
+	ipv6Servers []string
+	timeout     time.Duration
+}
+
+func New(provider Provider, opts ...Option) (gateway.IPRetriever, error) {
+	retriever := &DNSIPRetriever{
+		queryName:   provider.QueryName,
+		queryType:   provider.QueryType,
+		queryClass:  provider.QueryClass,
+		ipv4Servers: provider.IPv4Servers,
+		ipv6Servers: provider.IPv6Servers,
+		timeout:     DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(retriever)
+	}
+
+	if retriever.queryName == "" {
+		return nil, errors.New("a query name is required")
+	}
+	retriever.queryName = dns.Fqdn(retriever.queryName)
+
+	switch {
+	case strings.EqualFold(retriever.queryType, QueryTypeAddress):
+		retriever.queryType = QueryTypeAddress
+	case strings.EqualFold(retriever.queryType, QueryTypeTXT):
+		retriever.queryType = QueryTypeTXT
+	default:
+		return nil, fmt.Errorf("unknown query type: %s", retriever.queryType)
+	}
+
+	class, ok := dns.StringToClass[strings.ToUpper(retriever.queryClass)]
+	if !ok {
+		return nil, fmt.Errorf("unknown query class: %s", retriever.queryClass)
+	}
+	retriever.class = class
+
+	return retriever, nil
+}
+
+func (r *DNSIPRetriever) GetPublicIPv4(ctx context.Context) (net.IP, error) {
+	return r.retrieve(ctx, inet.IPv4, r.ipv4Servers)
+}
+
+func (r *DNSIPRetriever) GetPublicIPv6(ctx context.Context) (net.IP, error) {
+	return r.retrieve(ctx, inet.IPv6, r.ipv6Servers)
+}
+
+func (r *DNSIPRetriever) retrieve(ctx context.Context, family inet.Family, servers []string) (net.IP, error) {
+	if len(servers) == 0 {
+		return nil, gateway.ErrAddressFamilyNotSupported
+	}
+
+	var errs []error
+	for _, server := range servers {
+		ip, err := r.query(ctx, family, withDefaultPort(server))
+		if err == nil {
+			return ip, nil
+		}
+
+		log.Debug().Err(err).Str("server", server).Stringer("family", family).Msg("DNS query for public address failed")
+		errs = append(errs, fmt.Errorf("%s: %w", server, err))
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// query asks server for the address of family, reaching server over the same family so the answer reflects it.
+func (r *DNSIPRetriever) query(ctx context.Context, family inet.Family, server string) (net.IP, error) {
+	rrType := dns.TypeTXT
+	if r.queryType == QueryTypeAddress {
+		rrType = dns.StringToType[family.RecordType()]
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(r.queryName, rrType)
+	msg.Question[0].Qclass = r.class
+
+	client := &dns.Client{
+		Net:     "udp" + familySuffix(family),
+		Timeout: r.timeout,
+	}
+
+	response, _, err := client.ExchangeContext(ctx, msg, server)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Truncated {
+		client.Net = "tcp" + familySuffix(family)
+		response, _, err = client.ExchangeContext(ctx, msg, server)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if response.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("query for %s failed: %s", r.queryName, dns.RcodeToString[response.Rcode])
+	}
+
+	for _, answer := range response.Answer {
+		for _, ip := range answerAddresses(answer) {
+			if family.Matches(ip) {
+				return ip, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w for %s %s", ErrNoAddressInAnswer, r.queryName, dns.TypeToString[rrType])
+}
+
+// answerAddresses returns the addresses held by an A, AAAA or TXT record. TXT records may hold other text, such as
+// the EDNS client subnet Google adds, which is skipped.
+func answerAddresses(answer dns.RR) []net.IP {
+	switch answer := answer.(type) {
+	case *dns.A:
+		return []net.IP{answer.A}
+	case *dns.AAAA:
+		return []net.IP{answer.AAAA}
+	case *dns.TXT:
+		var ips []net.IP
+		for _, text := range answer.Txt {
+			if ip := net.ParseIP(strings.TrimSpace(text)); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+		return ips
+	default:
+		return nil
+	}
+}
+
+func familySuffix(family inet.Family) string {
+	if family == inet.IPv6 {
+		return "6"
+	}
+	return "4"
+}
+
+func withDefaultPort(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), "53")
+}
@@ -0,0 +1,75 @@
+package exec_ip_retriever
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	"github.com/awlsring/dynamic-ip-watcher/internal/pkg/command"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	EnvFamily = "FAMILY"
+)
+
+// ExecIPRetriever runs a command that prints the public address, for routers and scripts that already know it. The
+// command is told which family is wanted through the FAMILY variable, set to IPv4 or IPv6, and prints the address
+// alone on stdout. Printing nothing reports the family as not supported.
+type ExecIPRetriever struct {
+	args   []string
+	runner *command.Runner
+}
+
+func New(args []string, runner *command.Runner) (gateway.IPRetriever, error) {
+	if len(args) == 0 || args[0] == "" {
+		return nil, command.ErrEmptyCommand
+	}
+
+	return &ExecIPRetriever{
+		args:   args,
+		runner: runner,
+	}, nil
+}
+
+func (r *ExecIPRetriever) GetPublicIPv4(ctx context.Context) (net.IP, error) {
+	return r.retrieve(ctx, inet.IPv4)
+}
+
+func (r *ExecIPRetriever) GetPublicIPv6(ctx context.Context) (net.IP, error) {
+	return r.retrieve(ctx, inet.IPv6)
+}
+
+func (r *ExecIPRetriever) retrieve(ctx context.Context, family inet.Family) (net.IP, error) {
+	log.Debug().Str("Command", r.args[0]).Str("Family", family.String()).Msg("Running IP retriever command")
+	stdout, err := r.runner.Run(ctx, r.args, map[string]string{EnvFamily: family.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimSpace(string(stdout))
+	if text == "" {
+		return nil, gateway.ErrAddressFamilyNotSupported
+	}
+
+	ip := net.ParseIP(text)
+	if ip == nil {
+		return nil, fmt.Errorf("%s printed %q: %w", r.args[0], truncate(text), inet.ErrInvalidAddress)
+	}
+
+	if !family.Matches(ip) {
+		return nil, fmt.Errorf("%s printed %s when asked for an %s address", r.args[0], ip, family)
+	}
+
+	return ip, nil
+}
+
+func truncate(text string) string {
+	if len(text) > 64 {
+		return text[:64] + "..."
+	}
+	return text
+}
@@ -0,0 +1,62 @@
+package gateway_ip_retriever
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+const (
+	natpmpVersion             = 0
+	natpmpOpExternalAddress   = 0
+	natpmpResponseBit         = 128
+	natpmpExternalAddressSize = 12
+)
+
+var natpmpResults = map[uint16]string{
+	1: "unsupported version",
+	2: "not authorized",
+	3: "network failure",
+	4: "out of resources",
+	5: "unsupported opcode",
+}
+
+// natpmpExternalAddress sends a NAT-PMP external address request, as defined by RFC 6886.
+func (r *GatewayIPRetriever) natpmpExternalAddress(ctx context.Context) (net.IP, error) {
+	server, err := r.portMapperAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialUDP("udp4", nil, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	request := []byte{natpmpVersion, natpmpOpExternalAddress}
+	response, err := exchange(ctx, conn, request, func(response []byte) bool {
+		return len(response) >= 4 && response[0] == natpmpVersion && response[1] == natpmpResponseBit|natpmpOpExternalAddress
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result := binary.BigEndian.Uint16(response[2:4]); result != 0 {
+		return nil, fmt.Errorf("request failed: %s", resultText(natpmpResults, result))
+	}
+
+	if len(response) < natpmpExternalAddressSize {
+		return nil, fmt.Errorf("response too short")
+	}
+
+	return net.IPv4(response[8], response[9], response[10], response[11]).To4(), nil
+}
+
+func resultText(results map[uint16]string, result uint16) string {
+	if text, ok := results[result]; ok {
+		return text
+	}
+	return fmt.Sprintf("result code %d", result)
+}
@@ -0,0 +1,51 @@
+package gateway_ip_retriever
+
+import (
+	"net/http"
+	"time"
+)
+
+type Option func(*GatewayIPRetriever)
+
+// WithMethods sets the protocols tried, in order, from MethodUPnP, MethodNATPMP and MethodPCP.
+func WithMethods(methods []string) Option {
+	return func(r *GatewayIPRetriever) {
+		if len(methods) > 0 {
+			r.methods = methods
+		}
+	}
+}
+
+// WithGatewayAddress sets the router queried with NAT-PMP and PCP, as host or host:port, instead of the default
+// gateway.
+func WithGatewayAddress(address string) Option {
+	return func(r *GatewayIPRetriever) {
+		r.gatewayAddress = address
+	}
+}
+
+// WithSSDPAddress sets where UPnP discovery requests are sent, such as the router's address to avoid multicast.
+func WithSSDPAddress(address string) Option {
+	return func(r *GatewayIPRetriever) {
+		if address != "" {
+			r.ssdpAddress = address
+		}
+	}
+}
+
+// WithTimeout limits how long each method is given before the next is tried.
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *GatewayIPRetriever) {
+		if timeout > 0 {
+			r.timeout = timeout
+		}
+	}
+}
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(r *GatewayIPRetriever) {
+		if client != nil {
+			r.client = client
+		}
+	}
+}
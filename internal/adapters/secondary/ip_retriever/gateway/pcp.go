@@ -0,0 +1,109 @@
+package gateway_ip_retriever
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	pcpVersion     = 2
+	pcpOpMap       = 1
+	pcpResponseBit = 0x80
+	pcpMapSize     = 60
+	pcpProtocolUDP = 17
+
+	// the mapping only exists to learn the external address, so it is kept short and deleted right away
+	pcpMapLifetime = 30 * time.Second
+)
+
+var pcpResults = map[uint16]string{
+	1:  "unsupported version",
+	2:  "not authorized",
+	3:  "malformed request",
+	4:  "unsupported opcode",
+	5:  "unsupported option",
+	6:  "malformed option",
+	7:  "network failure",
+	8:  "no resources",
+	9:  "unsupported protocol",
+	10: "user exceeded quota",
+	11: "cannot provide external address",
+	12: "address mismatch",
+	13: "excessive remote peers",
+}
+
+// pcpExternalAddress learns the external address from the response to a PCP MAP request, as defined by RFC 6887,
+// since PCP has no request for the address alone. The mapping is deleted afterwards.
+func (r *GatewayIPRetriever) pcpExternalAddress(ctx context.Context) (net.IP, error) {
+	server, err := r.portMapperAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialUDP("udp4", nil, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var nonce [12]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+
+	local := conn.LocalAddr().(*net.UDPAddr)
+	response, err := pcpMap(ctx, conn, local, nonce, pcpMapLifetime)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+	defer cancel()
+	if _, err := pcpMap(deleteCtx, conn, local, nonce, 0); err != nil {
+		log.Debug().Err(err).Msg("Failed to delete PCP mapping, it will expire on its own")
+	}
+
+	return net.IP(response[44:60]), nil
+}
+
+func pcpMap(ctx context.Context, conn *net.UDPConn, local *net.UDPAddr, nonce [12]byte, lifetime time.Duration) ([]byte, error) {
+	request := make([]byte, pcpMapSize)
+	request[0] = pcpVersion
+	request[1] = pcpOpMap
+	binary.BigEndian.PutUint32(request[4:8], uint32(lifetime.Seconds()))
+	copy(request[8:24], local.IP.To16())
+	copy(request[24:36], nonce[:])
+	request[36] = pcpProtocolUDP
+	binary.BigEndian.PutUint16(request[40:42], uint16(local.Port))
+	// no suggested external port, and the IPv4-mapped unspecified address as no suggested external address
+	copy(request[44:60], net.IPv4zero.To16())
+
+	response, err := exchange(ctx, conn, request, func(response []byte) bool {
+		// servers that only speak NAT-PMP answer with their own version and an error
+		if len(response) >= 4 && response[0] == natpmpVersion {
+			return true
+		}
+		return len(response) >= pcpMapSize && response[0] == pcpVersion && response[1] == pcpResponseBit|pcpOpMap &&
+			bytes.Equal(response[24:36], nonce[:])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if response[0] == natpmpVersion {
+		return nil, fmt.Errorf("router only supports NAT-PMP")
+	}
+
+	if result := uint16(response[3]); result != 0 {
+		return nil, fmt.Errorf("request failed: %s", resultText(pcpResults, result))
+	}
+
+	return response, nil
+}
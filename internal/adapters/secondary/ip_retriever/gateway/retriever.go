@@ -0,0 +1,193 @@
+package gateway_ip_retriever
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/event"
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	MethodUPnP   = "upnp"
+	MethodNATPMP = "natpmp"
+	MethodPCP    = "pcp"
+
+	DefaultTimeout     = 2 * time.Second
+	DefaultSSDPAddress = "239.255.255.250:1900"
+	// NAT-PMP and PCP share a port on the router
+	DefaultPortMapperPort = 5351
+
+	// RFC 6886 starts retransmitting after 250ms, doubling the wait each time
+	initialRTO = 250 * time.Millisecond
+)
+
+var (
+	DefaultMethods = []string{MethodUPnP, MethodNATPMP, MethodPCP}
+
+	ErrGatewayBehindNAT = errors.New("router WAN address is behind another NAT")
+)
+
+// GatewayIPRetriever asks the local router for its WAN address with UPnP IGD, NAT-PMP or PCP, trying each method in
+// order. These protocols only report IPv4 addresses. A WAN address that is itself behind NAT, such as carrier-grade
+// NAT, is returned as an error along with a warning so it is never published.
+type GatewayIPRetriever struct {
+	methods        []string
+	gatewayAddress string
+	ssdpAddress    string
+	timeout        time.Duration
+	client         *http.Client
+}
+
+var _ gateway.DetailedIPRetriever = &GatewayIPRetriever{}
+
+func New(opts ...Option) (gateway.IPRetriever, error) {
+	retriever := &GatewayIPRetriever{
+		methods:     DefaultMethods,
+		ssdpAddress: DefaultSSDPAddress,
+		timeout:     DefaultTimeout,
+		client:      http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(retriever)
+	}
+
+	for _, method := range retriever.methods {
+		switch method {
+		case MethodUPnP, MethodNATPMP, MethodPCP:
+		default:
+			return nil, fmt.Errorf("unknown gateway method: %s", method)
+		}
+	}
+
+	return retriever, nil
+}
+
+func (r *GatewayIPRetriever) GetPublicIPv4(ctx context.Context) (net.IP, error) {
+	retrieval, err := r.RetrievePublicIP(ctx, inet.IPv4)
+	return retrieval.IP, err
+}
+
+func (r *GatewayIPRetriever) GetPublicIPv6(ctx context.Context) (net.IP, error) {
+	return nil, fmt.Errorf("router: %w", gateway.ErrAddressFamilyNotSupported)
+}
+
+// RetrievePublicIP returns the WAN address of the router, or a warning along with the error when the router is behind
+// another NAT.
+func (r *GatewayIPRetriever) RetrievePublicIP(ctx context.Context, family inet.Family) (gateway.Retrieval, error) {
+	if family != inet.IPv4 {
+		return gateway.Retrieval{}, fmt.Errorf("router: %w", gateway.ErrAddressFamilyNotSupported)
+	}
+
+	var errs []error
+	for _, method := range r.methods {
+		methodCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		ip, err := r.externalAddress(methodCtx, method)
+		cancel()
+
+		if err == nil && ip.To4() == nil {
+			err = fmt.Errorf("router returned %s, which is not an IPv4 address", ip)
+		}
+		if err == nil {
+			return checkWANAddress(ip.To4())
+		}
+
+		log.Debug().Err(err).Str("method", method).Msg("Failed to get WAN address from router")
+		errs = append(errs, fmt.Errorf("%s: %w", method, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return gateway.Retrieval{}, errors.Join(errs...)
+}
+
+func (r *GatewayIPRetriever) externalAddress(ctx context.Context, method string) (net.IP, error) {
+	switch method {
+	case MethodUPnP:
+		return r.upnpExternalAddress(ctx)
+	case MethodNATPMP:
+		return r.natpmpExternalAddress(ctx)
+	case MethodPCP:
+		return r.pcpExternalAddress(ctx)
+	default:
+		return nil, fmt.Errorf("unknown gateway method: %s", method)
+	}
+}
+
+// checkWANAddress rejects WAN addresses that are not public, as the router is then behind another NAT.
+func checkWANAddress(ip net.IP) (gateway.Retrieval, error) {
+	carrierGrade := inet.IsCarrierGradeNAT(ip)
+	if !carrierGrade && !ip.IsPrivate() {
+		return gateway.Retrieval{IP: ip}, nil
+	}
+
+	warning := gateway.Retrieval{Warnings: []event.Event{event.NewGatewayBehindNATEvent(ip, carrierGrade)}}
+	if carrierGrade {
+		return warning, fmt.Errorf("%w: %s is a carrier-grade NAT address", ErrGatewayBehindNAT, ip)
+	}
+	return warning, fmt.Errorf("%w: %s is a private address", ErrGatewayBehindNAT, ip)
+}
+
+// portMapperAddress returns the router's NAT-PMP and PCP address, detecting the default gateway unless one is set.
+func (r *GatewayIPRetriever) portMapperAddress() (*net.UDPAddr, error) {
+	address := r.gatewayAddress
+	if address == "" {
+		gatewayIP, err := defaultGateway()
+		if err != nil {
+			return nil, fmt.Errorf("could not detect the default gateway, set its address instead: %w", err)
+		}
+		address = gatewayIP.String()
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, fmt.Sprint(DefaultPortMapperPort))
+	}
+
+	return net.ResolveUDPAddr("udp4", address)
+}
+
+// exchange sends request over conn and returns the first response accepted by valid, retransmitting with a
+// doubling wait until ctx ends.
+func exchange(ctx context.Context, conn *net.UDPConn, request []byte, valid func([]byte) bool) ([]byte, error) {
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	buf := make([]byte, 1100)
+	for rto := initialRTO; ctx.Err() == nil; rto *= 2 {
+		if _, err := conn.Write(request); err != nil {
+			return nil, err
+		}
+
+		if err := conn.SetReadDeadline(time.Now().Add(rto)); err != nil {
+			return nil, err
+		}
+
+		for ctx.Err() == nil {
+			n, err := conn.Read(buf)
+			if isTimeout(err) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if valid(buf[:n]) {
+				return buf[:n], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no response from %s: %w", conn.RemoteAddr(), ctx.Err())
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,53 @@
+package gateway_ip_retriever
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	procNetRoute = "/proc/net/route"
+	rtfGateway   = 0x2
+)
+
+// defaultGateway reads the IPv4 default gateway from the Linux routing table.
+func defaultGateway() (net.IP, error) {
+	file, err := os.Open(procNetRoute)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags ..., with addresses printed as integers in host byte order
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[1] != "00000000" {
+			continue
+		}
+
+		flags, err := strconv.ParseUint(fields[3], 16, 16)
+		if err != nil || flags&rtfGateway == 0 {
+			continue
+		}
+
+		address, err := strconv.ParseUint(fields[2], 16, 32)
+		if err != nil {
+			continue
+		}
+
+		ip := make(net.IP, net.IPv4len)
+		binary.NativeEndian.PutUint32(ip, uint32(address))
+		return ip, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("no default route")
+}
@@ -0,0 +1,241 @@
+package gateway_ip_retriever
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	ssdpSearchTarget = "urn:schemas-upnp-org:device:InternetGatewayDevice:1"
+	ssdpWaitSeconds  = 1
+
+	maxDescriptionBytes = 1024 * 1024
+)
+
+var (
+	// services that can report the external address, in order of preference
+	wanServiceTypes = []string{
+		"urn:schemas-upnp-org:service:WANIPConnection:2",
+		"urn:schemas-upnp-org:service:WANIPConnection:1",
+		"urn:schemas-upnp-org:service:WANPPPConnection:1",
+	}
+
+	errNoIGD = errors.New("no internet gateway device found")
+)
+
+type upnpRoot struct {
+	URLBase string     `xml:"URLBase"`
+	Device  upnpDevice `xml:"device"`
+}
+
+type upnpDevice struct {
+	Services []upnpService `xml:"serviceList>service"`
+	Devices  []upnpDevice  `xml:"deviceList>device"`
+}
+
+type upnpService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+type soapEnvelope struct {
+	Body struct {
+		Response struct {
+			ExternalIPAddress string `xml:"NewExternalIPAddress"`
+		} `xml:"GetExternalIPAddressResponse"`
+		Fault *struct {
+			String      string `xml:"faultstring"`
+			Code        int    `xml:"detail>UPnPError>errorCode"`
+			Description string `xml:"detail>UPnPError>errorDescription"`
+		} `xml:"Fault"`
+	} `xml:"Body"`
+}
+
+// upnpExternalAddress discovers the router with SSDP and calls GetExternalIPAddress on its WAN connection service.
+func (r *GatewayIPRetriever) upnpExternalAddress(ctx context.Context) (net.IP, error) {
+	location, err := r.discoverIGD(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceType, controlURL, err := r.findWANService(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.getExternalIPAddress(ctx, serviceType, controlURL)
+}
+
+// discoverIGD sends an SSDP M-SEARCH for internet gateway devices and returns the description URL of the first
+// to answer.
+func (r *GatewayIPRetriever) discoverIGD(ctx context.Context) (*url.URL, error) {
+	ssdpAddr, err := net.ResolveUDPAddr("udp4", r.ssdpAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	search := strings.Join([]string{
+		"M-SEARCH * HTTP/1.1",
+		"HOST: " + DefaultSSDPAddress,
+		`MAN: "ssdp:discover"`,
+		fmt.Sprintf("MX: %d", ssdpWaitSeconds),
+		"ST: " + ssdpSearchTarget,
+		"", "",
+	}, "\r\n")
+
+	buf := make([]byte, 2048)
+	for rto := initialRTO; ctx.Err() == nil; rto *= 2 {
+		if _, err := conn.WriteToUDP([]byte(search), ssdpAddr); err != nil {
+			return nil, err
+		}
+
+		if err := conn.SetReadDeadline(time.Now().Add(rto)); err != nil {
+			return nil, err
+		}
+
+		for ctx.Err() == nil {
+			n, _, err := conn.ReadFromUDP(buf)
+			if isTimeout(err) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if location := parseSSDPResponse(buf[:n]); location != nil {
+				return location, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %w", errNoIGD, ctx.Err())
+}
+
+func parseSSDPResponse(response []byte) *url.URL {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(response)), nil)
+	if err != nil {
+		return nil
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !strings.EqualFold(resp.Header.Get("ST"), ssdpSearchTarget) {
+		return nil
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || location.Host == "" {
+		return nil
+	}
+	return location
+}
+
+// findWANService reads the device description at location and returns the preferred WAN connection service.
+func (r *GatewayIPRetriever) findWANService(ctx context.Context, location *url.URL) (string, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location.String(), nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("device description returned status code %d", resp.StatusCode)
+	}
+
+	var root upnpRoot
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, maxDescriptionBytes)).Decode(&root); err != nil {
+		return "", nil, fmt.Errorf("invalid device description: %w", err)
+	}
+
+	base := location
+	if root.URLBase != "" {
+		if base, err = url.Parse(root.URLBase); err != nil {
+			return "", nil, fmt.Errorf("invalid URLBase: %w", err)
+		}
+	}
+
+	services := root.Device.allServices()
+	for _, serviceType := range wanServiceTypes {
+		for _, service := range services {
+			if service.ServiceType != serviceType {
+				continue
+			}
+			controlURL, err := base.Parse(service.ControlURL)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid control URL: %w", err)
+			}
+			return serviceType, controlURL, nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("%w: no WAN connection service", errNoIGD)
+}
+
+func (d upnpDevice) allServices() []upnpService {
+	services := d.Services
+	for _, device := range d.Devices {
+		services = append(services, device.allServices()...)
+	}
+	return services
+}
+
+func (r *GatewayIPRetriever) getExternalIPAddress(ctx context.Context, serviceType string, controlURL *url.URL) (net.IP, error) {
+	body := `<?xml version="1.0"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body><u:GetExternalIPAddress xmlns:u="` + serviceType + `"></u:GetExternalIPAddress></s:Body>` +
+		`</s:Envelope>`
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, controlURL.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", `"`+serviceType+`#GetExternalIPAddress"`)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var envelope soapEnvelope
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, maxDescriptionBytes)).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("invalid SOAP response with status code %d: %w", resp.StatusCode, err)
+	}
+
+	if fault := envelope.Body.Fault; fault != nil {
+		if fault.Code != 0 {
+			return nil, fmt.Errorf("GetExternalIPAddress failed with UPnP error %d: %s", fault.Code, fault.Description)
+		}
+		return nil, fmt.Errorf("GetExternalIPAddress failed: %s", fault.String)
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(envelope.Body.Response.ExternalIPAddress))
+	if ip == nil {
+		return nil, fmt.Errorf("router returned no external address, it may not be connected")
+	}
+
+	return ip, nil
+}
@@ -0,0 +1,31 @@
+package http_ip_retriever
+
+type Option func(*HTTPIPRetriever)
+
+// WithHeaders sets headers on every request, such as an Authorization header for an internal service.
+func WithHeaders(headers map[string]string) Option {
+	return func(r *HTTPIPRetriever) {
+		if len(headers) > 0 {
+			r.headers = headers
+		}
+	}
+}
+
+// WithRegex extracts the address with pattern instead of reading the whole body. The first capture group is used
+// when the pattern has one.
+func WithRegex(pattern string) Option {
+	return func(r *HTTPIPRetriever) {
+		if pattern != "" {
+			r.pattern = pattern
+		}
+	}
+}
+
+// WithJSONPath extracts the address from a JSON body at path, such as "ip" or "$.wan.address".
+func WithJSONPath(path string) Option {
+	return func(r *HTTPIPRetriever) {
+		if path != "" {
+			r.jsonPath = path
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package http_ip_retriever
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	"github.com/awlsring/dynamic-ip-watcher/internal/pkg/jsonpath"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+)
+
+const (
+	IpifyIPv4URL     = "https://api.ipify.org"
+	IpifyIPv6URL     = "https://api6.ipify.org"
+	IcanhazipIPv4URL = "https://ipv4.icanhazip.com"
+	IcanhazipIPv6URL = "https://ipv6.icanhazip.com"
+
+	maxResponseBytes = 64 * 1024
+)
+
+// HTTPIPRetriever reads the public address from an HTTP response. By default the whole body must be the address, as
+// returned by services such as ipify or icanhazip. With a regex or JSON path the address is extracted instead, so
+// JSON APIs and router status pages can be used too. Each family has its own URL, which may be the same for both
+// when the response holds both addresses.
+type HTTPIPRetriever struct {
+	ipv4URL  string
+	ipv6URL  string
+	headers  map[string]string
+	pattern  string
+	regex    *regexp.Regexp
+	jsonPath string
+	client   *http.Client
+}
+
+// New creates a retriever for the given URLs. An empty URL reports the family as not supported.
+func New(ipv4URL, ipv6URL string, client *http.Client, opts ...Option) (gateway.IPRetriever, error) {
+	retriever := &HTTPIPRetriever{
+		ipv4URL: ipv4URL,
+		ipv6URL: ipv6URL,
+		client:  client,
+	}
+
+	for _, opt := range opts {
+		opt(retriever)
+	}
+
+	if retriever.pattern != "" && retriever.jsonPath != "" {
+		return nil, fmt.Errorf("only one of a regex or JSON path can be used")
+	}
+
+	if retriever.pattern != "" {
+		var err error
+		if retriever.regex, err = regexp.Compile(retriever.pattern); err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+
+	return retriever, nil
+}
+
+func (r *HTTPIPRetriever) GetPublicIPv4(ctx context.Context) (net.IP, error) {
+	return r.retrieve(ctx, inet.IPv4, r.ipv4URL)
+}
+
+func (r *HTTPIPRetriever) GetPublicIPv6(ctx context.Context) (net.IP, error) {
+	return r.retrieve(ctx, inet.IPv6, r.ipv6URL)
+}
+
+func (r *HTTPIPRetriever) retrieve(ctx context.Context, family inet.Family, url string) (net.IP, error) {
+	if url == "" {
+		return nil, gateway.ErrAddressFamilyNotSupported
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range r.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s returned status code %d", req.URL.Host, resp.StatusCode)
+	}
+
+	ip, err := r.extract(family, body)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", req.URL.Host, err)
+	}
+
+	return ip, nil
+}
+
+func (r *HTTPIPRetriever) extract(family inet.Family, body []byte) (net.IP, error) {
+	switch {
+	case r.regex != nil:
+		return r.extractRegex(family, body)
+	case r.jsonPath != "":
+		value, err := jsonpath.LookupString(body, r.jsonPath)
+		if err != nil {
+			return nil, fmt.Errorf("response has no usable %s: %w", r.jsonPath, err)
+		}
+		return parse(family, value)
+	default:
+		return parse(family, string(body))
+	}
+}
+
+// extractRegex returns the first match that is an address of family, using the first capture group of the regex
+// when it has one and the whole match otherwise.
+func (r *HTTPIPRetriever) extractRegex(family inet.Family, body []byte) (net.IP, error) {
+	group := 0
+	if r.regex.NumSubexp() > 0 {
+		group = 1
+	}
+
+	for _, match := range r.regex.FindAllSubmatch(body, -1) {
+		ip := net.ParseIP(strings.TrimSpace(string(match[group])))
+		if ip != nil && family.Matches(ip) {
+			return ip, nil
+		}
+	}
+
+	return nil, fmt.Errorf("response has no %s address matching %q", family, r.regex)
+}
+
+func parse(family inet.Family, value string) (net.IP, error) {
+	text := strings.TrimSpace(value)
+	ip := net.ParseIP(text)
+	if ip == nil {
+		return nil, fmt.Errorf("returned %q: %w", truncate(text), inet.ErrInvalidAddress)
+	}
+
+	if !family.Matches(ip) {
+		return nil, fmt.Errorf("returned %s when asked for an %s address", ip, family)
+	}
+
+	return ip, nil
+}
+
+func truncate(text string) string {
+	if len(text) > 64 {
+		return text[:64] + "..."
+	}
+	return text
+}
@@ -0,0 +1,116 @@
+package interface_ip_retriever
+
+import (
+	"bufio"
+	"encoding/hex"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	procIfInet6 = "/proc/net/if_inet6"
+
+	// address flags from linux/if_addr.h
+	ifaFlagTemporary  = 0x01
+	ifaFlagDADFailed  = 0x08
+	ifaFlagDeprecated = 0x20
+	ifaFlagTentative  = 0x40
+	ifaFlagPermanent  = 0x80
+)
+
+// Address is an address assigned to an interface. The flags are only known for IPv6 addresses on Linux.
+type Address struct {
+	Interface  string
+	IP         net.IP
+	PrefixLen  int
+	Temporary  bool
+	Deprecated bool
+	// Tentative is set while duplicate address detection is running or after it failed.
+	Tentative bool
+	// Permanent is set for statically configured addresses, as opposed to those from SLAAC or DHCPv6.
+	Permanent bool
+}
+
+// listAddresses returns the addresses of every interface that is up, or only of name when set.
+func listAddresses(name string) ([]Address, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	flags := ipv6Flags()
+
+	var addresses []Address
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || (name != "" && iface.Name != name) {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+
+			prefixLen, _ := ipNet.Mask.Size()
+			address := Address{
+				Interface: iface.Name,
+				IP:        ipNet.IP,
+				PrefixLen: prefixLen,
+			}
+
+			if ifaFlags, ok := flags[iface.Name+"/"+ipNet.IP.String()]; ok {
+				address.Temporary = ifaFlags&ifaFlagTemporary != 0
+				address.Deprecated = ifaFlags&ifaFlagDeprecated != 0
+				address.Tentative = ifaFlags&(ifaFlagTentative|ifaFlagDADFailed) != 0
+				address.Permanent = ifaFlags&ifaFlagPermanent != 0
+			}
+
+			addresses = append(addresses, address)
+		}
+	}
+
+	return addresses, nil
+}
+
+// ipv6Flags reads the flags of every IPv6 address from procfs, keyed by interface name and address. It is empty
+// where procfs is not available.
+func ipv6Flags() map[string]uint64 {
+	flags := map[string]uint64{}
+
+	file, err := os.Open(procIfInet6)
+	if err != nil {
+		return flags
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// address ifindex prefixlen scope flags name, all but the name in hex
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+
+		raw, err := hex.DecodeString(fields[0])
+		if err != nil || len(raw) != net.IPv6len {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[4], 16, 32)
+		if err != nil {
+			continue
+		}
+
+		flags[fields[5]+"/"+net.IP(raw).String()] = value
+	}
+
+	return flags
+}
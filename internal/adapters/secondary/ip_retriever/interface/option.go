@@ -0,0 +1,50 @@
+package interface_ip_retriever
+
+import (
+	"fmt"
+	"net"
+)
+
+type Option func(*InterfaceIPRetriever)
+
+// WithAllowPrivate allows RFC 1918 IPv4 addresses and IPv6 unique local addresses.
+func WithAllowPrivate(allow bool) Option {
+	return func(r *InterfaceIPRetriever) {
+		r.allowPrivate = allow
+	}
+}
+
+// WithTemporary allows temporary IPv6 privacy addresses, which change often and are skipped by default.
+func WithTemporary(include bool) Option {
+	return func(r *InterfaceIPRetriever) {
+		r.includeTemporary = include
+	}
+}
+
+// WithDeprecated allows IPv6 addresses past their preferred lifetime, which are skipped by default.
+func WithDeprecated(include bool) Option {
+	return func(r *InterfaceIPRetriever) {
+		r.includeDeprecated = include
+	}
+}
+
+// WithPrefixes only allows addresses within one of prefixes and never those within one of excludePrefixes.
+func WithPrefixes(prefixes, excludePrefixes []*net.IPNet) Option {
+	return func(r *InterfaceIPRetriever) {
+		r.prefixes = prefixes
+		r.excludePrefixes = excludePrefixes
+	}
+}
+
+// ParsePrefixes parses CIDR prefixes such as "2001:db8::/32" for WithPrefixes.
+func ParsePrefixes(cidrs []string) ([]*net.IPNet, error) {
+	var prefixes []*net.IPNet
+	for _, cidr := range cidrs {
+		_, prefix, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix %s: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
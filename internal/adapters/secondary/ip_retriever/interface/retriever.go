@@ -0,0 +1,127 @@
+package interface_ip_retriever
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/rs/zerolog/log"
+)
+
+// InterfaceIPRetriever reads the public address from an interface of this host, for hosts that hold it directly.
+// Loopback, link-local and tentative addresses are never used. Private addresses, including IPv6 unique local
+// addresses, and temporary and deprecated IPv6 addresses are skipped unless allowed. When several addresses remain,
+// statically configured addresses are preferred, then the lowest address, so the same address is picked every run.
+type InterfaceIPRetriever struct {
+	interfaceName     string
+	allowPrivate      bool
+	includeTemporary  bool
+	includeDeprecated bool
+	prefixes          []*net.IPNet
+	excludePrefixes   []*net.IPNet
+	listAddresses     func(name string) ([]Address, error)
+}
+
+// New creates a retriever for the named interface, or every interface that is up when name is empty.
+func New(name string, opts ...Option) gateway.IPRetriever {
+	retriever := &InterfaceIPRetriever{
+		interfaceName: name,
+		listAddresses: listAddresses,
+	}
+
+	for _, opt := range opts {
+		opt(retriever)
+	}
+
+	return retriever
+}
+
+func (r *InterfaceIPRetriever) GetPublicIPv4(ctx context.Context) (net.IP, error) {
+	return r.retrieve(inet.IPv4)
+}
+
+func (r *InterfaceIPRetriever) GetPublicIPv6(ctx context.Context) (net.IP, error) {
+	return r.retrieve(inet.IPv6)
+}
+
+func (r *InterfaceIPRetriever) retrieve(family inet.Family) (net.IP, error) {
+	addresses, err := r.listAddresses(r.interfaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []Address
+	for _, address := range addresses {
+		if !family.Matches(address.IP) {
+			continue
+		}
+		if reason := r.skipReason(address); reason != "" {
+			log.Debug().Str("interface", address.Interface).Str("ip", address.IP.String()).Msgf("Skipping %s address", reason)
+			continue
+		}
+		candidates = append(candidates, address)
+	}
+
+	if len(candidates) == 0 {
+		if r.interfaceName != "" {
+			return nil, fmt.Errorf("no usable %s address on interface %s", family, r.interfaceName)
+		}
+		return nil, fmt.Errorf("no usable %s address on any interface", family)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Permanent != candidates[j].Permanent {
+			return candidates[i].Permanent
+		}
+		return bytes.Compare(candidates[i].IP.To16(), candidates[j].IP.To16()) < 0
+	})
+
+	return normalize(family, candidates[0].IP), nil
+}
+
+// skipReason returns why the address cannot be used, or an empty string if it can.
+func (r *InterfaceIPRetriever) skipReason(address Address) string {
+	ip := address.IP
+	switch {
+	case ip.IsLoopback():
+		return "loopback"
+	case ip.IsLinkLocalUnicast():
+		return "link-local"
+	case !ip.IsGlobalUnicast():
+		return "non-unicast"
+	case address.Tentative:
+		return "tentative"
+	case ip.IsPrivate() && !r.allowPrivate:
+		return "private"
+	case address.Temporary && !r.includeTemporary:
+		return "temporary"
+	case address.Deprecated && !r.includeDeprecated:
+		return "deprecated"
+	case len(r.prefixes) > 0 && !containedIn(r.prefixes, ip):
+		return "unlisted prefix"
+	case containedIn(r.excludePrefixes, ip):
+		return "excluded prefix"
+	default:
+		return ""
+	}
+}
+
+func containedIn(prefixes []*net.IPNet, ip net.IP) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(family inet.Family, ip net.IP) net.IP {
+	if family == inet.IPv4 {
+		return ip.To4()
+	}
+	return ip.To16()
+}
@@ -2,8 +2,10 @@ package ipapi_ip_retriever
 
 import (
 	"context"
+	"fmt"
 	"net"
 
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
 	ipapi "github.com/awlsring/dynamic-ip-watcher/internal/pkg/ip-api"
 	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
 )
@@ -24,5 +26,15 @@ func (r *IPRetrieverIPAPI) GetPublicIPv4(ctx context.Context) (net.IP, error) {
 		return nil, err
 	}
 
-	return net.ParseIP(response.Query), nil
+	ip := net.ParseIP(response.Query)
+	if ip == nil {
+		return nil, fmt.Errorf("ip-api returned %q: %w", response.Query, inet.ErrInvalidAddress)
+	}
+
+	return ip, nil
+}
+
+// ip-api.com is only reachable over IPv4, so it can only report the public IPv4 address.
+func (r *IPRetrieverIPAPI) GetPublicIPv6(ctx context.Context) (net.IP, error) {
+	return nil, fmt.Errorf("ip-api: %w", gateway.ErrAddressFamilyNotSupported)
 }
@@ -0,0 +1,172 @@
+package stun_ip_retriever
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	headerLength = 20
+	magicCookie  = 0x2112A442
+
+	typeBindingRequest  = 0x0001
+	typeBindingSuccess  = 0x0101
+	typeBindingError    = 0x0111
+	attrMappedAddress   = 0x0001
+	attrErrorCode       = 0x0009
+	attrXorMappedAddr   = 0x0020
+	attrXorMappedLegacy = 0x8020
+	attrOtherAddress    = 0x802C
+
+	addressFamilyIPv4 = 0x01
+	addressFamilyIPv6 = 0x02
+)
+
+var (
+	errNotStun          = errors.New("not a STUN message")
+	errTransactionID    = errors.New("transaction ID does not match")
+	errNoMappedAddress  = errors.New("response has no mapped address")
+	errMalformedAddress = errors.New("malformed address attribute")
+)
+
+type transactionID [12]byte
+
+// bindingResponse holds the attributes of a Binding success response used to find the public address and test
+// NAT behaviour. OtherAddress is only sent by servers supporting RFC 5780.
+type bindingResponse struct {
+	MappedAddress *net.UDPAddr
+	OtherAddress  *net.UDPAddr
+}
+
+func newBindingRequest() (transactionID, []byte, error) {
+	var id transactionID
+	if _, err := rand.Read(id[:]); err != nil {
+		return id, nil, err
+	}
+
+	request := make([]byte, headerLength)
+	binary.BigEndian.PutUint16(request[0:2], typeBindingRequest)
+	binary.BigEndian.PutUint16(request[2:4], 0)
+	binary.BigEndian.PutUint32(request[4:8], magicCookie)
+	copy(request[8:20], id[:])
+
+	return id, request, nil
+}
+
+// parseBindingResponse decodes a response to the request with id. XOR-MAPPED-ADDRESS is preferred over the
+// MAPPED-ADDRESS sent by RFC 3489 servers.
+func parseBindingResponse(id transactionID, message []byte) (bindingResponse, error) {
+	var response bindingResponse
+
+	if len(message) < headerLength || message[0]&0xC0 != 0 || binary.BigEndian.Uint32(message[4:8]) != magicCookie {
+		return response, errNotStun
+	}
+
+	if [12]byte(message[8:20]) != id {
+		return response, errTransactionID
+	}
+
+	messageType := binary.BigEndian.Uint16(message[0:2])
+	length := int(binary.BigEndian.Uint16(message[2:4]))
+	if headerLength+length > len(message) {
+		return response, errNotStun
+	}
+
+	var mapped, xorMapped *net.UDPAddr
+	attributes := message[headerLength : headerLength+length]
+	for len(attributes) >= 4 {
+		attrType := binary.BigEndian.Uint16(attributes[0:2])
+		attrLength := int(binary.BigEndian.Uint16(attributes[2:4]))
+		if 4+attrLength > len(attributes) {
+			return response, errNotStun
+		}
+		value := attributes[4 : 4+attrLength]
+
+		var err error
+		switch attrType {
+		case attrErrorCode:
+			if messageType == typeBindingError {
+				return response, parseErrorCode(value)
+			}
+		case attrMappedAddress:
+			mapped, err = parseAddress(value, nil)
+		case attrXorMappedAddr, attrXorMappedLegacy:
+			xorMapped, err = parseAddress(value, message[4:20])
+		case attrOtherAddress:
+			response.OtherAddress, err = parseAddress(value, nil)
+		}
+		if err != nil {
+			return response, err
+		}
+
+		// attributes are padded to a multiple of four bytes
+		next := 4 + (attrLength+3)&^3
+		if next > len(attributes) {
+			break
+		}
+		attributes = attributes[next:]
+	}
+
+	if messageType == typeBindingError {
+		return response, errors.New("binding request rejected")
+	}
+	if messageType != typeBindingSuccess {
+		return response, fmt.Errorf("unexpected message type 0x%04x", messageType)
+	}
+
+	response.MappedAddress = xorMapped
+	if response.MappedAddress == nil {
+		response.MappedAddress = mapped
+	}
+	if response.MappedAddress == nil {
+		return response, errNoMappedAddress
+	}
+
+	return response, nil
+}
+
+// parseAddress decodes an address attribute. For the XOR variants, key is the magic cookie followed by the
+// transaction ID, which the port and address are XORed with.
+func parseAddress(value []byte, key []byte) (*net.UDPAddr, error) {
+	if len(value) < 4 {
+		return nil, errMalformedAddress
+	}
+
+	var ip net.IP
+	switch value[1] {
+	case addressFamilyIPv4:
+		if len(value) != 8 {
+			return nil, errMalformedAddress
+		}
+		ip = make(net.IP, net.IPv4len)
+	case addressFamilyIPv6:
+		if len(value) != 20 {
+			return nil, errMalformedAddress
+		}
+		ip = make(net.IP, net.IPv6len)
+	default:
+		return nil, errMalformedAddress
+	}
+
+	port := binary.BigEndian.Uint16(value[2:4])
+	copy(ip, value[4:])
+	if key != nil {
+		port ^= binary.BigEndian.Uint16(key[0:2])
+		for i := range ip {
+			ip[i] ^= key[i]
+		}
+	}
+
+	return &net.UDPAddr{IP: ip, Port: int(port)}, nil
+}
+
+func parseErrorCode(value []byte) error {
+	if len(value) < 4 {
+		return errors.New("binding request rejected")
+	}
+	code := int(value[2]&0x07)*100 + int(value[3])
+	return fmt.Errorf("binding request rejected with %d %s", code, string(value[4:]))
+}
@@ -0,0 +1,21 @@
+package stun_ip_retriever
+
+import "time"
+
+type Option func(*STUNIPRetriever)
+
+// WithTimeout limits how long each server is waited on, including retransmissions.
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *STUNIPRetriever) {
+		if timeout > 0 {
+			r.timeout = timeout
+		}
+	}
+}
+
+// WithNATMappingDetection turns the extra requests made to classify the NAT on or off. It is on by default.
+func WithNATMappingDetection(enabled bool) Option {
+	return func(r *STUNIPRetriever) {
+		r.detectNATMapping = enabled
+	}
+}
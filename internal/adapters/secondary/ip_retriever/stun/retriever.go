@@ -0,0 +1,273 @@
+package stun_ip_retriever
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultPort    = 3478
+	DefaultTimeout = 3 * time.Second
+
+	// RFC 5389 starts retransmitting after 500ms, doubling the wait each time
+	initialRTO     = 500 * time.Millisecond
+	maxMessageSize = 1500
+)
+
+var (
+	DefaultServers = []string{"stun.l.google.com:19302", "stun.cloudflare.com:3478"}
+)
+
+// STUNIPRetriever learns the public address from the XOR-MAPPED-ADDRESS of a STUN Binding response, trying the
+// servers in order. Each family is queried over that family. After finding the address it tests how the NAT maps
+// ports, using the RFC 5780 alternate address when the server offers one and the next server otherwise, and returns
+// the result with the address.
+type STUNIPRetriever struct {
+	servers          []string
+	timeout          time.Duration
+	detectNATMapping bool
+}
+
+var _ gateway.DetailedIPRetriever = &STUNIPRetriever{}
+
+// New creates a retriever for servers, given as host or host:port. DefaultServers are used when none are given.
+func New(servers []string, opts ...Option) gateway.IPRetriever {
+	if len(servers) == 0 {
+		servers = DefaultServers
+	}
+
+	retriever := &STUNIPRetriever{
+		servers:          servers,
+		timeout:          DefaultTimeout,
+		detectNATMapping: true,
+	}
+
+	for _, opt := range opts {
+		opt(retriever)
+	}
+
+	return retriever
+}
+
+func (r *STUNIPRetriever) GetPublicIPv4(ctx context.Context) (net.IP, error) {
+	retrieval, err := r.RetrievePublicIP(ctx, inet.IPv4)
+	return retrieval.IP, err
+}
+
+func (r *STUNIPRetriever) GetPublicIPv6(ctx context.Context) (net.IP, error) {
+	retrieval, err := r.RetrievePublicIP(ctx, inet.IPv6)
+	return retrieval.IP, err
+}
+
+// RetrievePublicIP returns the mapped address along with the NAT mapping, unless detection is turned off or there was
+// nothing to compare against.
+func (r *STUNIPRetriever) RetrievePublicIP(ctx context.Context, family inet.Family) (gateway.Retrieval, error) {
+	network := networkOf(family)
+
+	// every request is sent from one socket so the mappings it is given can be compared
+	conn, err := net.ListenUDP(network, nil)
+	if err != nil {
+		return gateway.Retrieval{}, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var errs []error
+	for i, server := range r.servers {
+		serverAddr, err := resolve(ctx, network, server)
+		if err == nil {
+			var response bindingResponse
+			response, err = r.bind(ctx, conn, serverAddr)
+			if err == nil && !family.Matches(response.MappedAddress.IP) {
+				err = fmt.Errorf("mapped address %s is not %s", response.MappedAddress.IP, family)
+			}
+			if err == nil {
+				retrieval := gateway.Retrieval{IP: response.MappedAddress.IP}
+				if r.detectNATMapping {
+					retrieval.NATMapping = r.detectMapping(ctx, conn, network, serverAddr, response, r.servers[i+1:])
+				}
+				return retrieval, nil
+			}
+		}
+
+		log.Debug().Err(err).Str("server", server).Stringer("family", family).Msg("STUN binding request failed")
+		errs = append(errs, fmt.Errorf("%s: %w", server, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return gateway.Retrieval{}, errors.Join(errs...)
+}
+
+// bind sends a Binding request to server, retransmitting until a response arrives or the timeout passes.
+func (r *STUNIPRetriever) bind(ctx context.Context, conn *net.UDPConn, server *net.UDPAddr) (bindingResponse, error) {
+	id, request, err := newBindingRequest()
+	if err != nil {
+		return bindingResponse{}, err
+	}
+
+	deadline := time.Now().Add(r.timeout)
+	buf := make([]byte, maxMessageSize)
+	for rto := initialRTO; time.Now().Before(deadline); rto *= 2 {
+		if _, err := conn.WriteToUDP(request, server); err != nil {
+			return bindingResponse{}, contextError(ctx, err)
+		}
+
+		wait := time.Now().Add(rto)
+		if wait.After(deadline) {
+			wait = deadline
+		}
+		if err := conn.SetReadDeadline(wait); err != nil {
+			return bindingResponse{}, err
+		}
+
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			if err != nil {
+				return bindingResponse{}, contextError(ctx, err)
+			}
+
+			// late responses to earlier requests and stray packets are ignored
+			if !from.IP.Equal(server.IP) || from.Port != server.Port {
+				continue
+			}
+			response, err := parseBindingResponse(id, buf[:n])
+			if errors.Is(err, errNotStun) || errors.Is(err, errTransactionID) {
+				continue
+			}
+			return response, err
+		}
+	}
+
+	return bindingResponse{}, fmt.Errorf("no response from %s within %s", server, r.timeout)
+}
+
+func (r *STUNIPRetriever) detectMapping(ctx context.Context, conn *net.UDPConn, network string, server *net.UDPAddr, response bindingResponse, otherServers []string) inet.NATMapping {
+	mapping := r.natMapping(ctx, conn, network, server, response, otherServers)
+	if mapping == "" {
+		log.Debug().Msg("Could not determine NAT mapping behaviour")
+		return ""
+	}
+
+	log.Debug().Str("mapping", string(mapping)).Msg("Determined NAT mapping behaviour")
+	return mapping
+}
+
+// natMapping classifies the NAT following the mapping tests of RFC 5780, comparing the address mapped for requests
+// to server with those to its alternate address. Without an alternate address, the mapping for the next server that
+// responds is compared instead, which cannot tell the two dependent mappings apart. An empty mapping is returned
+// when there is nothing to compare against.
+func (r *STUNIPRetriever) natMapping(ctx context.Context, conn *net.UDPConn, network string, server *net.UDPAddr, response bindingResponse, otherServers []string) inet.NATMapping {
+	if isLocalAddress(response.MappedAddress.IP) {
+		return inet.NATMappingNone
+	}
+
+	other := response.OtherAddress
+	if other != nil && !other.IP.Equal(server.IP) {
+		alternateIP, err := r.bind(ctx, conn, &net.UDPAddr{IP: other.IP, Port: server.Port})
+		if err != nil {
+			return ""
+		}
+		if sameAddress(alternateIP.MappedAddress, response.MappedAddress) {
+			return inet.NATMappingEndpointIndependent
+		}
+
+		alternateIPAndPort, err := r.bind(ctx, conn, other)
+		if err != nil {
+			return ""
+		}
+		if sameAddress(alternateIPAndPort.MappedAddress, alternateIP.MappedAddress) {
+			return inet.NATMappingAddressDependent
+		}
+		return inet.NATMappingAddressAndPortDependent
+	}
+
+	for _, otherServer := range otherServers {
+		otherAddr, err := resolve(ctx, network, otherServer)
+		if err != nil || otherAddr.IP.Equal(server.IP) {
+			continue
+		}
+		otherResponse, err := r.bind(ctx, conn, otherAddr)
+		if err != nil {
+			continue
+		}
+		if sameAddress(otherResponse.MappedAddress, response.MappedAddress) {
+			return inet.NATMappingEndpointIndependent
+		}
+		return inet.NATMappingEndpointDependent
+	}
+
+	return ""
+}
+
+// resolve looks up server using only addresses of the network's family.
+func resolve(ctx context.Context, network, server string) (*net.UDPAddr, error) {
+	host, portText, err := net.SplitHostPort(server)
+	if err != nil {
+		host, portText = server, strconv.Itoa(DefaultPort)
+	}
+
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in %s", server)
+	}
+
+	ipNetwork := "ip4"
+	if network == "udp6" {
+		ipNetwork = "ip6"
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, ipNetwork, host)
+	if err != nil {
+		return nil, err
+	}
+
+	return &net.UDPAddr{IP: net.IP(addrs[0].AsSlice()), Port: port}, nil
+}
+
+func networkOf(family inet.Family) string {
+	if family == inet.IPv6 {
+		return "udp6"
+	}
+	return "udp4"
+}
+
+func sameAddress(a, b *net.UDPAddr) bool {
+	return a.IP.Equal(b.IP) && a.Port == b.Port
+}
+
+func isLocalAddress(ip net.IP) bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// contextError reports the context's error when a socket operation failed because the context ended.
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
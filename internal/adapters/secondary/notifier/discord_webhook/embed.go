@@ -0,0 +1,83 @@
+package discord_webhook
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/event"
+)
+
+// Discord rejects embeds exceeding these lengths.
+// https://discord.com/developers/docs/resources/message#embed-object-embed-limits
+const (
+	maxTitleLength       = 256
+	maxDescriptionLength = 4096
+	maxFieldNameLength   = 256
+	maxFieldValueLength  = 1024
+	maxFields            = 25
+)
+
+// buildEmbed renders e as an embed colored by its severity, with its description and fields.
+func buildEmbed(e event.Event) DiscordEmbed {
+	p := event.Present(e)
+	meta := e.Meta()
+	embed := DiscordEmbed{
+		Title:       p.Title,
+		Description: p.Description,
+		Color:       e.Severity().Color(),
+		Footer:      footer(meta),
+	}
+	if p.Preformatted {
+		embed.Description = codeBlock(p.Description)
+	}
+	if !meta.Timestamp.IsZero() {
+		embed.Timestamp = meta.Timestamp.UTC().Format(time.RFC3339)
+	}
+
+	for _, f := range p.Fields {
+		var lines []string
+		for _, line := range f.Lines {
+			lines = append(lines, lineValue(line))
+		}
+		embed.Fields = append(embed.Fields, DiscordEmbedField{Name: f.Name, Value: strings.Join(lines, "\n"), Inline: f.Inline})
+	}
+
+	return truncateEmbed(embed)
+}
+
+// lineValue shows the literal of line as inline code.
+func lineValue(line event.Line) string {
+	if line.Literal == "" {
+		return line.Text
+	}
+	return fmt.Sprintf("`%s`%s", line.Literal, line.Text)
+}
+
+// codeBlock fences s, shortened to fit in a description.
+func codeBlock(s string) string {
+	s = event.Truncate(strings.ReplaceAll(s, "```", "'''"), maxDescriptionLength-8)
+	return "```\n" + s + "\n```"
+}
+
+// footer shows where the event came from, or is nil when neither the host nor the run is known.
+func footer(meta *event.Metadata) *DiscordEmbedFooter {
+	origin := meta.Origin()
+	if origin == "" {
+		return nil
+	}
+	return &DiscordEmbedFooter{Text: origin}
+}
+
+func truncateEmbed(embed DiscordEmbed) DiscordEmbed {
+	embed.Title = event.Truncate(embed.Title, maxTitleLength)
+	embed.Description = event.Truncate(embed.Description, maxDescriptionLength)
+	if len(embed.Fields) > maxFields {
+		embed.Fields = embed.Fields[:maxFields]
+	}
+	for i := range embed.Fields {
+		embed.Fields[i].Name = event.Truncate(embed.Fields[i].Name, maxFieldNameLength)
+		embed.Fields[i].Value = event.Truncate(embed.Fields[i].Value, maxFieldValueLength)
+	}
+	return embed
+}
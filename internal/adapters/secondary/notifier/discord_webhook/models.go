@@ -1,39 +1,46 @@
 package discord_webhook
 
+// https://discord.com/developers/docs/resources/message#embed-object
 type DiscordEmbed struct {
-	Title       string `json:"title,omitempty"`
-	Type        string `json:"type,omitempty"`
-	Description string `json:"description,omitempty"`
-	URL         string `json:"url,omitempty"`
-	Timestamp   string `json:"timestamp,omitempty"`
-	Color       int    `json:"color,omitempty"`
-	Footer      struct {
-		Text    string `json:"text,omitempty"`
-		IconURL string `json:"icon_url,omitempty"`
-	} `json:"footer,omitempty"`
-	Image struct {
-		URL string `json:"url,omitempty"`
-	} `json:"image,omitempty"`
-	Thumbnail struct {
-		URL string `json:"url,omitempty"`
-	} `json:"thumbnail,omitempty"`
-	Video struct {
-		URL string `json:"url,omitempty"`
-	} `json:"video,omitempty"`
-	Provider struct {
-		Name string `json:"name,omitempty"`
-		URL  string `json:"url,omitempty"`
-	} `json:"provider,omitempty"`
-	Author struct {
-		Name    string `json:"name,omitempty"`
-		URL     string `json:"url,omitempty"`
-		IconURL string `json:"icon_url,omitempty"`
-	} `json:"author,omitempty"`
-	Fields []struct {
-		Name   string `json:"name,omitempty"`
-		Value  string `json:"value,omitempty"`
-		Inline bool   `json:"inline,omitempty"`
-	} `json:"fields,omitempty"`
+	Title       string                `json:"title,omitempty"`
+	Type        string                `json:"type,omitempty"`
+	Description string                `json:"description,omitempty"`
+	URL         string                `json:"url,omitempty"`
+	Timestamp   string                `json:"timestamp,omitempty"`
+	Color       int                   `json:"color,omitempty"`
+	Footer      *DiscordEmbedFooter   `json:"footer,omitempty"`
+	Image       *DiscordEmbedMedia    `json:"image,omitempty"`
+	Thumbnail   *DiscordEmbedMedia    `json:"thumbnail,omitempty"`
+	Video       *DiscordEmbedMedia    `json:"video,omitempty"`
+	Provider    *DiscordEmbedProvider `json:"provider,omitempty"`
+	Author      *DiscordEmbedAuthor   `json:"author,omitempty"`
+	Fields      []DiscordEmbedField   `json:"fields,omitempty"`
+}
+
+type DiscordEmbedFooter struct {
+	Text    string `json:"text,omitempty"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+type DiscordEmbedMedia struct {
+	URL string `json:"url,omitempty"`
+}
+
+type DiscordEmbedProvider struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+type DiscordEmbedAuthor struct {
+	Name    string `json:"name,omitempty"`
+	URL     string `json:"url,omitempty"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+type DiscordEmbedField struct {
+	Name   string `json:"name,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Inline bool   `json:"inline,omitempty"`
 }
 
 // https://discord.com/developers/docs/resources/webhook#execute-webhook
@@ -44,3 +51,10 @@ type DiscordWebhookMessage struct {
 	TTS       bool           `json:"tts,omitempty"`
 	Embeds    []DiscordEmbed `json:"embeds,omitempty"`
 }
+
+// https://discord.com/developers/docs/topics/rate-limits#exceeding-a-rate-limit
+type DiscordRateLimitResponse struct {
+	Message    string  `json:"message"`
+	RetryAfter float64 `json:"retry_after"`
+	Global     bool    `json:"global"`
+}
@@ -0,0 +1,13 @@
+package discord_webhook
+
+type Option func(*DiscordWebhookNotifier)
+
+// WithPlainText sends events as plain message content instead of embeds, such as for channels bridged to other chat
+// services that drop embeds.
+func WithPlainText(plainText bool) Option {
+	return func(d *DiscordWebhookNotifier) {
+		if plainText {
+			d.plainText = plainText
+		}
+	}
+}
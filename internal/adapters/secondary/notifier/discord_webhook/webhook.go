@@ -4,10 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/event"
+	"github.com/awlsring/dynamic-ip-watcher/internal/pkg/retry"
 	"github.com/rs/zerolog/log"
 )
 
@@ -20,9 +27,10 @@ type DiscordWebhookNotifier struct {
 	url       string
 	avatarUrl string
 	username  string
+	plainText bool
 }
 
-func New(url, avatarUrl, username string, client *http.Client) *DiscordWebhookNotifier {
+func New(url, avatarUrl, username string, client *http.Client, opts ...Option) *DiscordWebhookNotifier {
 	notifier := &DiscordWebhookNotifier{
 		client:    client,
 		url:       url,
@@ -34,9 +42,15 @@ func New(url, avatarUrl, username string, client *http.Client) *DiscordWebhookNo
 		notifier.username = Username
 	}
 
+	for _, opt := range opts {
+		opt(notifier)
+	}
+
 	return notifier
 }
 
+// SendEventMessage posts event as an embed, or as plain content when configured to. A rate limited message is sent
+// again once Discord's retry_after has passed, up to retry.MaxRetries times.
 func (d *DiscordWebhookNotifier) SendEventMessage(ctx context.Context, event event.Event) error {
 	if event == nil {
 		log.Warn().Msg("event provided was empty, not sending.")
@@ -44,8 +58,13 @@ func (d *DiscordWebhookNotifier) SendEventMessage(ctx context.Context, event eve
 	}
 
 	discordMessage := DiscordWebhookMessage{
-		Username: d.username,
-		Content:  event.AsMessage(),
+		Username:  d.username,
+		AvatarUrl: d.avatarUrl,
+	}
+	if d.plainText {
+		discordMessage.Content = event.AsMessage()
+	} else {
+		discordMessage.Embeds = []DiscordEmbed{buildEmbed(event)}
 	}
 
 	payloadBytes, err := json.Marshal(discordMessage)
@@ -53,22 +72,68 @@ func (d *DiscordWebhookNotifier) SendEventMessage(ctx context.Context, event eve
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewBuffer(payloadBytes))
+	return retry.OnRateLimit(ctx, "Discord webhook", func() (time.Duration, error) {
+		return d.post(ctx, payloadBytes)
+	})
+}
+
+// post sends payload to the webhook. When rate limited it returns how long to wait before sending it again along
+// with the error.
+func (d *DiscordWebhookNotifier) post(ctx context.Context, payload []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(payload))
 	if err != nil {
-		return err
+		return 0, d.redact(err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := d.client.Do(req)
 	if err != nil {
-		return err
+		return 0, d.redact(err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("failed to send message, status code: %d", resp.StatusCode)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		wait := retryAfter(resp.Header, body)
+		return wait, fmt.Errorf("failed to send message, rate limited for %s", wait)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return 0, fmt.Errorf("failed to send message, status code: %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return 0, nil
+}
+
+// redact removes the webhook url, which holds the token of the webhook, from errors of the http client so it is not
+// logged. Only its host is kept.
+func (d *DiscordWebhookNotifier) redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		redacted := "<redacted>"
+		if webhookURL, parseErr := url.Parse(d.url); parseErr == nil && webhookURL.Host != "" {
+			redacted = webhookURL.Scheme + "://" + webhookURL.Host + "/<redacted>"
+		}
+		urlErr.URL = strings.ReplaceAll(urlErr.URL, d.url, redacted)
+	}
+	return err
+}
+
+// retryAfter reads how long to wait from the retry_after of a rate limited response, which is in seconds, falling
+// back to the Retry-After header.
+func retryAfter(header http.Header, body []byte) time.Duration {
+	var rateLimit DiscordRateLimitResponse
+	if err := json.Unmarshal(body, &rateLimit); err == nil && rateLimit.RetryAfter > 0 {
+		return time.Duration(rateLimit.RetryAfter * float64(time.Second))
+	}
+
+	if seconds, err := strconv.ParseFloat(header.Get("Retry-After"), 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
 	}
 
-	return nil
+	return retry.DefaultRetryAfter
 }
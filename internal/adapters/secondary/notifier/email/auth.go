@@ -0,0 +1,50 @@
+package email_notifier
+
+import (
+	"errors"
+	"net/smtp"
+	"strings"
+)
+
+// loginAuth implements the LOGIN mechanism, which net/smtp does not provide but some servers such as Office 365 still
+// require. Like smtp.PlainAuth, it refuses to send credentials over an unencrypted connection to a remote server.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func LoginAuth(username, password, host string) smtp.Auth {
+	return &loginAuth{username: username, password: password, host: host}
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+// Next answers the username and password prompts, which are sent in that order.
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	prompt := strings.ToLower(strings.TrimSpace(string(fromServer)))
+	switch {
+	case strings.HasPrefix(prompt, "username"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(prompt, "password"):
+		return []byte(a.password), nil
+	default:
+		return nil, errors.New("unexpected LOGIN prompt: " + string(fromServer))
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
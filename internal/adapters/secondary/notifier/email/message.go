@@ -0,0 +1,229 @@
+package email_notifier
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/event"
+)
+
+// maxHeaderLineLength is the length RFC 5322 recommends folding the lines of a header to.
+const maxHeaderLineLength = 78
+
+// SubjectData is available to the subject template, such as "[dynamic-ip-watcher] {{.Title}} on {{.Host}}".
+type SubjectData struct {
+	Title    string
+	Host     string
+	RunID    string
+	Kind     event.Kind
+	Severity event.Severity
+}
+
+// field is a labelled value of an event, rendered as a row of a table in the HTML part.
+type field struct {
+	Name  string
+	Value string
+}
+
+// body is the content shared by the text and HTML parts of a message. Description is an error message when
+// Preformatted is set.
+type body struct {
+	Title        string
+	Color        string
+	Description  string
+	Preformatted bool
+	Fields       []field
+	Footer       string
+}
+
+var htmlBody = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #222;">
+<div style="border-left: 4px solid {{.Color}}; padding-left: 12px;">
+<h2 style="margin: 0 0 12px 0;">{{.Title}}</h2>
+{{- if .Description}}
+{{if .Preformatted}}<pre style="background: #f4f4f4; padding: 8px; white-space: pre-wrap;">{{.Description}}</pre>{{else}}<p>{{.Description}}</p>{{end}}
+{{- end}}
+{{- if .Fields}}
+<table style="border-collapse: collapse;">
+{{- range .Fields}}
+<tr><th style="text-align: left; padding: 2px 12px 2px 0; vertical-align: top;">{{.Name}}</th><td style="padding: 2px 0; white-space: pre-wrap;">{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</div>
+{{- if .Footer}}
+<p style="color: #888; font-size: 12px;">{{.Footer}}</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// buildMessage renders e as a multipart/alternative message with a text and an HTML part, with the subject rendered
+// from subject.
+func buildMessage(e event.Event, from *mail.Address, to []*mail.Address, subject *template.Template) ([]byte, error) {
+	p := event.Present(e)
+	meta := e.Meta()
+
+	var subjectLine strings.Builder
+	err := subject.Execute(&subjectLine, SubjectData{
+		Title:    p.Title,
+		Host:     meta.Host,
+		RunID:    meta.RunID,
+		Kind:     e.Kind(),
+		Severity: e.Severity(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render subject: %w", err)
+	}
+	encodedSubject, err := encodeSubject(subjectLine.String())
+	if err != nil {
+		return nil, err
+	}
+
+	content := body{
+		Title:        p.Title,
+		Color:        fmt.Sprintf("#%06X", e.Severity().Color()),
+		Description:  p.Description,
+		Preformatted: p.Preformatted,
+		Footer:       meta.Footer(),
+	}
+	for _, f := range p.Fields {
+		var lines []string
+		for _, line := range f.Lines {
+			lines = append(lines, line.String())
+		}
+		content.Fields = append(content.Fields, field{Name: f.Name, Value: strings.Join(lines, "\n")})
+	}
+
+	var html bytes.Buffer
+	if err := htmlBody.Execute(&html, content); err != nil {
+		return nil, err
+	}
+
+	date := meta.Timestamp
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	var recipients []string
+	for _, address := range to {
+		recipients = append(recipients, address.String())
+	}
+
+	var message bytes.Buffer
+	writer := multipart.NewWriter(&message)
+
+	headers := []struct{ name, value string }{
+		{"From", from.String()},
+		{"To", strings.Join(recipients, ", ")},
+		{"Subject", encodedSubject},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", messageID(from)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", writer.Boundary())},
+	}
+	var header bytes.Buffer
+	for _, h := range headers {
+		fmt.Fprintf(&header, "%s: %s\r\n", h.name, h.value)
+	}
+	header.WriteString("\r\n")
+
+	if err := writePart(writer, "text/plain", []byte(textBody(content))); err != nil {
+		return nil, err
+	}
+	if err := writePart(writer, "text/html", html.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return append(header.Bytes(), message.Bytes()...), nil
+}
+
+// encodeSubject collapses the whitespace of a rendered subject onto a single line, so a template cannot add headers
+// with a line break, then encodes it and folds it to fit the lines of the Subject header.
+func encodeSubject(subject string) (string, error) {
+	subject = strings.Join(strings.Fields(subject), " ")
+	if subject == "" {
+		return "", ErrEmptySubject
+	}
+	return foldHeader("Subject", mime.QEncoding.Encode("utf-8", subject)), nil
+}
+
+// foldHeader breaks value onto continuation lines at its spaces, keeping each line of the header within
+// maxHeaderLineLength unless a single word is longer. The first word moves off the line of the name only when that
+// lets it fit.
+func foldHeader(name, value string) string {
+	var folded strings.Builder
+	lineLength := len(name) + len(": ")
+	for i, word := range strings.Split(value, " ") {
+		separator := min(i, 1)
+		switch {
+		case lineLength+separator+len(word) > maxHeaderLineLength && (i > 0 || 1+len(word) <= maxHeaderLineLength):
+			folded.WriteString("\r\n ")
+			lineLength = 1
+		case separator > 0:
+			folded.WriteString(" ")
+			lineLength++
+		}
+		folded.WriteString(word)
+		lineLength += len(word)
+	}
+	return folded.String()
+}
+
+// writePart adds content as a quoted-printable part, which keeps lines within the length SMTP allows.
+func writePart(writer *multipart.Writer, contentType string, content []byte) error {
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := quotedprintable.NewWriter(part)
+	if _, err := encoder.Write(content); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func textBody(content body) string {
+	lines := []string{content.Title, ""}
+	if content.Description != "" {
+		lines = append(lines, content.Description, "")
+	}
+	for _, f := range content.Fields {
+		lines = append(lines, fmt.Sprintf("%s: %s", f.Name, strings.ReplaceAll(f.Value, "\n", "\n  ")))
+	}
+	if content.Footer != "" {
+		lines = append(lines, "", "-- ", content.Footer)
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// messageID generates a unique Message-ID in the domain of the sender.
+func messageID(from *mail.Address) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from.Address, "@"); at >= 0 {
+		domain = from.Address[at+1:]
+	}
+
+	random := make([]byte, 12)
+	_, _ = rand.Read(random)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain)
+}
@@ -0,0 +1,247 @@
+package email_notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/event"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TLSModeSTARTTLS = "starttls"
+	TLSModeImplicit = "implicit"
+	TLSModeNone     = "none"
+)
+
+const (
+	AuthPlain = "plain"
+	AuthLogin = "login"
+)
+
+const (
+	DefaultSubject = "[dynamic-ip-watcher] {{.Title}} on {{.Host}}"
+	DefaultTimeout = 30 * time.Second
+)
+
+var (
+	ErrMissingHost          = errors.New("smtp host is required")
+	ErrMissingRecipients    = errors.New("at least one recipient is required")
+	ErrUnknownTLSMode       = errors.New("unknown smtp tls mode")
+	ErrUnknownAuthMechanism = errors.New("unknown smtp auth mechanism")
+	ErrStartTLSUnsupported  = errors.New("smtp server does not support STARTTLS")
+	ErrAuthUnsupported      = errors.New("smtp server does not support authentication")
+	ErrEmptySubject         = errors.New("subject template rendered an empty subject")
+)
+
+// EmailNotifier sends events as multipart text and HTML email through an SMTP server.
+type EmailNotifier struct {
+	host               string
+	port               int
+	from               *mail.Address
+	to                 []*mail.Address
+	tlsMode            string
+	authMechanism      string
+	username           string
+	password           string
+	subject            string
+	subjectTemplate    *template.Template
+	insecureSkipVerify bool
+	timeout            time.Duration
+}
+
+// New creates a notifier sending from the address from to every address of to, through the server at host and port.
+// Addresses may include a name, such as "Watcher <watcher@example.com>". A port of 0 uses the default port of the
+// TLS mode: 587 for STARTTLS, 465 for implicit TLS and 25 otherwise.
+func New(host string, port int, from string, to []string, opts ...Option) (gateway.Notifier, error) {
+	if host == "" {
+		return nil, ErrMissingHost
+	}
+	if len(to) == 0 {
+		return nil, ErrMissingRecipients
+	}
+
+	fromAddress, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+
+	var toAddresses []*mail.Address
+	for _, recipient := range to {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", recipient, err)
+		}
+		toAddresses = append(toAddresses, address)
+	}
+
+	notifier := &EmailNotifier{
+		host:    host,
+		port:    port,
+		from:    fromAddress,
+		to:      toAddresses,
+		tlsMode: TLSModeSTARTTLS,
+		subject: DefaultSubject,
+		timeout: DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(notifier)
+	}
+
+	switch notifier.tlsMode {
+	case TLSModeSTARTTLS, TLSModeImplicit, TLSModeNone:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTLSMode, notifier.tlsMode)
+	}
+
+	switch notifier.authMechanism {
+	case "":
+		if notifier.username != "" {
+			notifier.authMechanism = AuthPlain
+		}
+	case AuthPlain, AuthLogin:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAuthMechanism, notifier.authMechanism)
+	}
+
+	if notifier.port == 0 {
+		notifier.port = defaultPort(notifier.tlsMode)
+	}
+
+	notifier.subjectTemplate, err = template.New("subject").Parse(notifier.subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+
+	return notifier, nil
+}
+
+func defaultPort(tlsMode string) int {
+	switch tlsMode {
+	case TLSModeSTARTTLS:
+		return 587
+	case TLSModeImplicit:
+		return 465
+	default:
+		return 25
+	}
+}
+
+func (n *EmailNotifier) SendEventMessage(ctx context.Context, event event.Event) error {
+	if event == nil {
+		log.Warn().Msg("event provided was empty, not sending.")
+		return nil
+	}
+
+	message, err := buildMessage(event, n.from, n.to, n.subjectTemplate)
+	if err != nil {
+		return err
+	}
+
+	return n.send(ctx, message)
+}
+
+// send delivers message to every recipient in a single SMTP session, upgrading the connection with STARTTLS and
+// authenticating first when configured to.
+func (n *EmailNotifier) send(ctx context.Context, message []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	tlsConfig := &tls.Config{
+		ServerName:         n.host,
+		InsecureSkipVerify: n.insecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	addr := net.JoinHostPort(n.host, strconv.Itoa(n.port))
+	dialer := &net.Dialer{}
+
+	var conn net.Conn
+	var err error
+	if n.tlsMode == TLSModeImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	// net/smtp does not take a context, so bound every read and write by its deadline instead
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if hostname, err := os.Hostname(); err == nil {
+		if err := client.Hello(hostname); err != nil {
+			return err
+		}
+	}
+
+	if n.tlsMode == TLSModeSTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return ErrStartTLSUnsupported
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	if n.authMechanism != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return ErrAuthUnsupported
+		}
+		if err := client.Auth(n.auth()); err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.from.Address); err != nil {
+		return err
+	}
+	for _, recipient := range n.to {
+		if err := client.Rcpt(recipient.Address); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", recipient.Address, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (n *EmailNotifier) auth() smtp.Auth {
+	if n.authMechanism == AuthLogin {
+		return LoginAuth(n.username, n.password, n.host)
+	}
+	return smtp.PlainAuth("", n.username, n.password, n.host)
+}
@@ -0,0 +1,52 @@
+package email_notifier
+
+import "time"
+
+type Option func(*EmailNotifier)
+
+// WithTLSMode selects how the connection is encrypted: TLSModeSTARTTLS, the default, TLSModeImplicit or TLSModeNone.
+func WithTLSMode(mode string) Option {
+	return func(n *EmailNotifier) {
+		if mode != "" {
+			n.tlsMode = mode
+		}
+	}
+}
+
+// WithAuth authenticates as username with mechanism, AuthPlain or AuthLogin, defaulting to AuthPlain.
+func WithAuth(mechanism, username, password string) Option {
+	return func(n *EmailNotifier) {
+		if username != "" {
+			n.authMechanism = mechanism
+			n.username = username
+			n.password = password
+		}
+	}
+}
+
+// WithSubject renders the subject from a text/template over SubjectData instead of DefaultSubject.
+func WithSubject(subject string) Option {
+	return func(n *EmailNotifier) {
+		if subject != "" {
+			n.subject = subject
+		}
+	}
+}
+
+// WithInsecureSkipVerify accepts any certificate from the server, such as a self-signed one on a local relay.
+func WithInsecureSkipVerify(insecure bool) Option {
+	return func(n *EmailNotifier) {
+		if insecure {
+			n.insecureSkipVerify = insecure
+		}
+	}
+}
+
+// WithTimeout bounds the whole exchange with the server instead of DefaultTimeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(n *EmailNotifier) {
+		if timeout > 0 {
+			n.timeout = timeout
+		}
+	}
+}
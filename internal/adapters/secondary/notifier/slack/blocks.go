@@ -0,0 +1,104 @@
+package slack_notifier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/event"
+)
+
+// Slack rejects blocks exceeding these lengths.
+// https://api.slack.com/reference/block-kit/blocks#section
+const (
+	maxTextLength     = 3000
+	maxFieldLength    = 2000
+	maxFieldsPerBlock = 10
+	maxFallbackLength = 4000
+)
+
+const (
+	typeMarkdown = "mrkdwn"
+	typeSection  = "section"
+	typeContext  = "context"
+	// contextDateFormat is rendered by Slack in the reader's time zone, with fallbackDateFormat shown by clients
+	// that cannot.
+	contextDateFormat  = "{date_short_pretty} at {time}"
+	fallbackDateFormat = "2006-01-02 15:04:05 MST"
+)
+
+// buildMessage renders e as a title section followed by its description and fields, in an attachment colored by
+// its severity. The plain text of the event is kept as the fallback for notifications.
+func buildMessage(e event.Event) SlackMessage {
+	p := event.Present(e)
+
+	blocks := []SlackBlock{section("*" + escape(p.Title) + "*")}
+	switch {
+	case p.Preformatted:
+		blocks = append(blocks, section(codeBlock(p.Description)))
+	case p.Description != "":
+		blocks = append(blocks, section(escape(p.Description)))
+	}
+	for start := 0; start < len(p.Fields); start += maxFieldsPerBlock {
+		end := min(start+maxFieldsPerBlock, len(p.Fields))
+		block := SlackBlock{Type: typeSection}
+		for _, f := range p.Fields[start:end] {
+			var lines []string
+			for _, line := range f.Lines {
+				lines = append(lines, lineValue(line))
+			}
+			block.Fields = append(block.Fields, markdown(event.Truncate(fmt.Sprintf("*%s*\n%s", escape(f.Name), strings.Join(lines, "\n")), maxFieldLength)))
+		}
+		blocks = append(blocks, block)
+	}
+	if context := contextText(e.Meta()); context != "" {
+		blocks = append(blocks, SlackBlock{Type: typeContext, Elements: []SlackText{markdown(context)}})
+	}
+
+	return SlackMessage{
+		Text: event.Truncate(escape(e.AsMessage()), maxFallbackLength),
+		Attachments: []SlackAttachment{{
+			Color:  fmt.Sprintf("#%06X", e.Severity().Color()),
+			Blocks: blocks,
+		}},
+	}
+}
+
+// lineValue escapes line as mrkdwn, showing its literal as inline code.
+func lineValue(line event.Line) string {
+	if line.Literal == "" {
+		return escape(line.Text)
+	}
+	return fmt.Sprintf("`%s`%s", escape(line.Literal), escape(line.Text))
+}
+
+// contextText shows where and when the event happened.
+func contextText(meta *event.Metadata) string {
+	var parts []string
+	if origin := meta.Origin(); origin != "" {
+		parts = append(parts, escape(origin))
+	}
+	if !meta.Timestamp.IsZero() {
+		parts = append(parts, fmt.Sprintf("<!date^%d^%s|%s>", meta.Timestamp.Unix(), contextDateFormat, meta.Timestamp.UTC().Format(fallbackDateFormat)))
+	}
+	return strings.Join(parts, " • ")
+}
+
+func section(text string) SlackBlock {
+	return SlackBlock{Type: typeSection, Text: &SlackText{Type: typeMarkdown, Text: event.Truncate(text, maxTextLength)}}
+}
+
+func markdown(text string) SlackText {
+	return SlackText{Type: typeMarkdown, Text: text}
+}
+
+// codeBlock fences s, shortened to fit in a section.
+func codeBlock(s string) string {
+	s = event.Truncate(escape(strings.ReplaceAll(s, "```", "'''")), maxTextLength-8)
+	return "```\n" + s + "\n```"
+}
+
+// escape replaces the characters Slack reserves for links and mentions.
+// https://api.slack.com/reference/surfaces/formatting#escaping
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
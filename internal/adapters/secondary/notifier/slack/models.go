@@ -0,0 +1,41 @@
+package slack_notifier
+
+// SlackMessage is the payload of both an incoming webhook and chat.postMessage. Channel is only used by
+// chat.postMessage, and Text is shown in notifications and by clients that cannot render the attachments.
+// https://api.slack.com/methods/chat.postMessage
+type SlackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Text        string            `json:"text"`
+	Username    string            `json:"username,omitempty"`
+	IconURL     string            `json:"icon_url,omitempty"`
+	Attachments []SlackAttachment `json:"attachments,omitempty"`
+}
+
+// SlackAttachment wraps blocks in a bar of Color, such as "#2ECC71".
+// https://api.slack.com/reference/messaging/attachments
+type SlackAttachment struct {
+	Color  string       `json:"color,omitempty"`
+	Blocks []SlackBlock `json:"blocks"`
+}
+
+// https://api.slack.com/reference/block-kit/blocks
+type SlackBlock struct {
+	Type     string      `json:"type"`
+	Text     *SlackText  `json:"text,omitempty"`
+	Fields   []SlackText `json:"fields,omitempty"`
+	Elements []SlackText `json:"elements,omitempty"`
+}
+
+// https://api.slack.com/reference/block-kit/composition-objects#text
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SlackAPIResponse is returned by every Web API method, which answers with a 200 status even when it fails.
+// https://api.slack.com/web#responses
+type SlackAPIResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Warning string `json:"warning"`
+}
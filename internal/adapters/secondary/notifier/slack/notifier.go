@@ -0,0 +1,170 @@
+package slack_notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/event"
+	"github.com/awlsring/dynamic-ip-watcher/internal/pkg/retry"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultAPIURL = "https://slack.com/api/"
+)
+
+var (
+	ErrMissingWebhookURL = errors.New("slack webhook url is required")
+	ErrMissingToken      = errors.New("slack bot token is required")
+	ErrMissingChannel    = errors.New("slack channel is required to post with a bot token")
+)
+
+// SlackNotifier posts events either to an incoming webhook or, with a bot token, through chat.postMessage.
+type SlackNotifier struct {
+	client     *http.Client
+	webhookURL string
+	token      string
+	channel    string
+	apiURL     string
+	username   string
+	iconURL    string
+}
+
+// NewWebhook creates a notifier posting to the channel an incoming webhook was created for.
+func NewWebhook(webhookURL string, client *http.Client, opts ...Option) (gateway.Notifier, error) {
+	if webhookURL == "" {
+		return nil, ErrMissingWebhookURL
+	}
+
+	return newNotifier(&SlackNotifier{client: client, webhookURL: webhookURL}, opts), nil
+}
+
+// NewBot creates a notifier posting to channel, a channel ID or name, with chat.postMessage. The bot must have the
+// chat:write scope and be a member of the channel.
+func NewBot(token, channel string, client *http.Client, opts ...Option) (gateway.Notifier, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+	if channel == "" {
+		return nil, ErrMissingChannel
+	}
+
+	return newNotifier(&SlackNotifier{client: client, token: token, channel: channel}, opts), nil
+}
+
+func newNotifier(notifier *SlackNotifier, opts []Option) *SlackNotifier {
+	notifier.apiURL = DefaultAPIURL
+	for _, opt := range opts {
+		opt(notifier)
+	}
+	return notifier
+}
+
+// SendEventMessage posts event as Block Kit sections in an attachment colored by its severity. A rate limited
+// message is sent again once the Retry-After header has passed, up to retry.MaxRetries times.
+func (s *SlackNotifier) SendEventMessage(ctx context.Context, event event.Event) error {
+	if event == nil {
+		log.Warn().Msg("event provided was empty, not sending.")
+		return nil
+	}
+
+	message := buildMessage(event)
+	message.Channel = s.channel
+	message.Username = s.username
+	message.IconURL = s.iconURL
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return retry.OnRateLimit(ctx, "Slack", func() (time.Duration, error) {
+		return s.post(ctx, payload)
+	})
+}
+
+// post sends payload to the webhook or chat.postMessage. When rate limited it returns how long to wait before
+// sending it again along with the error.
+func (s *SlackNotifier) post(ctx context.Context, payload []byte) (time.Duration, error) {
+	endpoint := s.webhookURL
+	if s.token != "" {
+		endpoint = strings.TrimSuffix(s.apiURL, "/") + "/chat.postMessage"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return 0, s.redact(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, s.redact(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		wait := retryAfter(resp.Header)
+		return wait, fmt.Errorf("failed to send message, rate limited for %s", wait)
+	case resp.StatusCode != http.StatusOK:
+		// webhooks describe the failure in the body, such as "invalid_payload" or "channel_is_archived"
+		return 0, fmt.Errorf("failed to send message, status code: %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	case s.token == "":
+		return 0, nil
+	}
+
+	var apiResponse SlackAPIResponse
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return 0, fmt.Errorf("failed to decode chat.postMessage response: %w", err)
+	}
+	if !apiResponse.OK {
+		return 0, fmt.Errorf("failed to send message: chat.postMessage returned %s", apiResponse.Error)
+	}
+	if apiResponse.Warning != "" {
+		log.Debug().Str("warning", apiResponse.Warning).Msg("chat.postMessage returned a warning")
+	}
+
+	return 0, nil
+}
+
+// redact removes the webhook url, which is the credential of the webhook, from errors of the http client so it is not
+// logged. Only its host is kept.
+func (s *SlackNotifier) redact(err error) error {
+	var urlErr *url.Error
+	if s.webhookURL != "" && errors.As(err, &urlErr) {
+		redacted := "<redacted>"
+		if webhookURL, parseErr := url.Parse(s.webhookURL); parseErr == nil && webhookURL.Host != "" {
+			redacted = webhookURL.Scheme + "://" + webhookURL.Host + "/<redacted>"
+		}
+		urlErr.URL = strings.ReplaceAll(urlErr.URL, s.webhookURL, redacted)
+	}
+	return err
+}
+
+// retryAfter reads how long to wait from the Retry-After header of a rate limited response, which is in seconds.
+func retryAfter(header http.Header) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return retry.DefaultRetryAfter
+}
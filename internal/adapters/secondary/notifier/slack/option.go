@@ -0,0 +1,31 @@
+package slack_notifier
+
+type Option func(*SlackNotifier)
+
+// WithAPIURL sends chat.postMessage requests to url instead of DefaultAPIURL, such as a local stand-in.
+func WithAPIURL(url string) Option {
+	return func(s *SlackNotifier) {
+		if url != "" {
+			s.apiURL = url
+		}
+	}
+}
+
+// WithUsername overrides the name messages are posted as. Slack only honors it for bots with the
+// chat:write.customize scope and for legacy webhooks.
+func WithUsername(username string) Option {
+	return func(s *SlackNotifier) {
+		if username != "" {
+			s.username = username
+		}
+	}
+}
+
+// WithIconURL overrides the avatar messages are posted with, under the same conditions as WithUsername.
+func WithIconURL(iconURL string) Option {
+	return func(s *SlackNotifier) {
+		if iconURL != "" {
+			s.iconURL = iconURL
+		}
+	}
+}
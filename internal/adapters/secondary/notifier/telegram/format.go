@@ -0,0 +1,133 @@
+package telegram_notifier
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/event"
+)
+
+const (
+	ParseModeHTML       = "HTML"
+	ParseModeMarkdownV2 = "MarkdownV2"
+)
+
+const (
+	// maxMessageLength is the number of characters Telegram allows in a message.
+	maxMessageLength = 4096
+	// maxErrorLength keeps an error well within maxMessageLength, leaving room for the rest of the event.
+	maxErrorLength = 3000
+)
+
+// formatter marks up text in one of the parse modes Telegram supports. Every method escapes its input.
+type formatter interface {
+	text(s string) string
+	bold(s string) string
+	italic(s string) string
+	code(s string) string
+	pre(s string) string
+}
+
+func newFormatter(parseMode string) formatter {
+	if parseMode == ParseModeMarkdownV2 {
+		return markdownFormatter{}
+	}
+	return htmlFormatter{}
+}
+
+// https://core.telegram.org/bots/api#html-style
+type htmlFormatter struct{}
+
+func (htmlFormatter) text(s string) string   { return html.EscapeString(s) }
+func (htmlFormatter) bold(s string) string   { return "<b>" + html.EscapeString(s) + "</b>" }
+func (htmlFormatter) italic(s string) string { return "<i>" + html.EscapeString(s) + "</i>" }
+func (htmlFormatter) code(s string) string   { return "<code>" + html.EscapeString(s) + "</code>" }
+func (htmlFormatter) pre(s string) string    { return "<pre>" + html.EscapeString(s) + "</pre>" }
+
+// https://core.telegram.org/bots/api#markdownv2-style
+type markdownFormatter struct{}
+
+var (
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+		">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+	)
+	markdownCodeEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+)
+
+func (markdownFormatter) text(s string) string   { return markdownEscaper.Replace(s) }
+func (markdownFormatter) bold(s string) string   { return "*" + markdownEscaper.Replace(s) + "*" }
+func (markdownFormatter) italic(s string) string { return "_" + markdownEscaper.Replace(s) + "_" }
+func (markdownFormatter) code(s string) string   { return "`" + markdownCodeEscaper.Replace(s) + "`" }
+func (markdownFormatter) pre(s string) string {
+	return "```\n" + markdownCodeEscaper.Replace(s) + "\n```"
+}
+
+// formatMessage renders e as a bold title followed by its description, a line per field and a footer naming where the
+// event came from. A field with several lines lists them below its name. Lines that would take the message past
+// maxMessageLength are left out.
+func formatMessage(f formatter, e event.Event) string {
+	p := event.Present(e)
+
+	lines := []string{f.bold(p.Title)}
+	switch {
+	case p.Preformatted:
+		lines = append(lines, f.pre(event.Truncate(p.Description, maxErrorLength)))
+	case p.Description != "":
+		lines = append(lines, f.text(p.Description))
+	}
+	if len(p.Fields) > 0 {
+		lines = append(lines, "")
+		for _, field := range p.Fields {
+			if len(field.Lines) == 1 {
+				lines = append(lines, f.bold(field.Name+":")+" "+lineValue(f, field.Lines[0]))
+				continue
+			}
+			lines = append(lines, f.bold(field.Name+":"))
+			for _, line := range field.Lines {
+				lines = append(lines, lineValue(f, line))
+			}
+		}
+	}
+	var footer []string
+	if text := e.Meta().Footer(); text != "" {
+		footer = []string{"", f.italic(text)}
+	}
+
+	return joinLines(f, lines, footer)
+}
+
+// joinLines joins lines followed by footer, keeping only as many lines as fit in maxMessageLength. Lines are left out
+// whole, as cutting one could split its markup, and an ellipsis marks where.
+func joinLines(f formatter, lines, footer []string) string {
+	message := strings.Join(append(lines, footer...), "\n")
+	if utf8.RuneCountInString(message) <= maxMessageLength {
+		return message
+	}
+
+	cut := f.text("…")
+	budget := maxMessageLength - utf8.RuneCountInString(strings.Join(append([]string{cut}, footer...), "\n"))
+	var kept []string
+	for _, line := range lines {
+		// the line break after each line counts as well
+		length := utf8.RuneCountInString(line) + 1
+		if length > budget {
+			break
+		}
+		kept = append(kept, line)
+		budget -= length
+	}
+
+	return strings.Join(append(append(kept, cut), footer...), "\n")
+}
+
+// lineValue marks up line, showing its literal as inline code. The text is shortened to maxErrorLength, as it may
+// hold an error.
+func lineValue(f formatter, line event.Line) string {
+	text := event.Truncate(line.Text, maxErrorLength)
+	if line.Literal == "" {
+		return f.text(text)
+	}
+	return f.code(line.Literal) + f.text(text)
+}
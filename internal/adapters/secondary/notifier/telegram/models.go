@@ -0,0 +1,31 @@
+package telegram_notifier
+
+// https://core.telegram.org/bots/api#sendmessage
+type SendMessageRequest struct {
+	ChatID              string              `json:"chat_id"`
+	MessageThreadID     int                 `json:"message_thread_id,omitempty"`
+	Text                string              `json:"text"`
+	ParseMode           string              `json:"parse_mode,omitempty"`
+	DisableNotification bool                `json:"disable_notification,omitempty"`
+	LinkPreviewOptions  *LinkPreviewOptions `json:"link_preview_options,omitempty"`
+}
+
+// https://core.telegram.org/bots/api#linkpreviewoptions
+type LinkPreviewOptions struct {
+	IsDisabled bool `json:"is_disabled"`
+}
+
+// APIResponse is returned by every Bot API method. Parameters carries how long to wait when rate limited.
+// https://core.telegram.org/bots/api#making-requests
+type APIResponse struct {
+	OK          bool                `json:"ok"`
+	Description string              `json:"description"`
+	ErrorCode   int                 `json:"error_code"`
+	Parameters  *ResponseParameters `json:"parameters"`
+}
+
+// https://core.telegram.org/bots/api#responseparameters
+type ResponseParameters struct {
+	MigrateToChatID int64 `json:"migrate_to_chat_id"`
+	RetryAfter      int   `json:"retry_after"`
+}
@@ -0,0 +1,147 @@
+package telegram_notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/event"
+	"github.com/awlsring/dynamic-ip-watcher/internal/pkg/retry"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultAPIURL = "https://api.telegram.org"
+)
+
+var (
+	ErrMissingToken     = errors.New("telegram bot token is required")
+	ErrMissingChatID    = errors.New("telegram chat id is required")
+	ErrUnknownParseMode = errors.New("unknown telegram parse mode")
+)
+
+// TelegramNotifier sends events to a chat with the sendMessage method of the Bot API.
+type TelegramNotifier struct {
+	client     *http.Client
+	token      string
+	chatID     string
+	apiURL     string
+	threadID   int
+	parseMode  string
+	silentInfo bool
+}
+
+// New creates a notifier sending as the bot of token to chatID, either the numeric ID of a chat or the @username of
+// a public channel.
+func New(token, chatID string, client *http.Client, opts ...Option) (gateway.Notifier, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+	if chatID == "" {
+		return nil, ErrMissingChatID
+	}
+
+	notifier := &TelegramNotifier{
+		client:    client,
+		token:     token,
+		chatID:    chatID,
+		apiURL:    DefaultAPIURL,
+		parseMode: ParseModeHTML,
+	}
+
+	for _, opt := range opts {
+		opt(notifier)
+	}
+
+	if notifier.parseMode != ParseModeHTML && notifier.parseMode != ParseModeMarkdownV2 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownParseMode, notifier.parseMode)
+	}
+
+	return notifier, nil
+}
+
+// SendEventMessage sends the event as a formatted message, silently when it is of info severity and silent info is
+// enabled. A rate limited message is sent again once the retry_after of the response has passed, up to retry.MaxRetries
+// times.
+func (t *TelegramNotifier) SendEventMessage(ctx context.Context, e event.Event) error {
+	if e == nil {
+		log.Warn().Msg("event provided was empty, not sending.")
+		return nil
+	}
+
+	request := SendMessageRequest{
+		ChatID:              t.chatID,
+		MessageThreadID:     t.threadID,
+		Text:                formatMessage(newFormatter(t.parseMode), e),
+		ParseMode:           t.parseMode,
+		DisableNotification: t.silentInfo && e.Severity() == event.SeverityInfo,
+		LinkPreviewOptions:  &LinkPreviewOptions{IsDisabled: true},
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	return retry.OnRateLimit(ctx, "Telegram", func() (time.Duration, error) {
+		return t.post(ctx, payload)
+	})
+}
+
+// post calls sendMessage with payload. When rate limited it returns how long to wait before sending it again along
+// with the error.
+func (t *TelegramNotifier) post(ctx context.Context, payload []byte) (time.Duration, error) {
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimSuffix(t.apiURL, "/"), t.token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return 0, t.redact(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return 0, t.redact(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return 0, t.redact(err)
+	}
+
+	var apiResponse APIResponse
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return 0, fmt.Errorf("failed to send message, status code: %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	if apiResponse.OK {
+		return 0, nil
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		wait := retry.DefaultRetryAfter
+		if apiResponse.Parameters != nil && apiResponse.Parameters.RetryAfter > 0 {
+			wait = time.Duration(apiResponse.Parameters.RetryAfter) * time.Second
+		}
+		return wait, fmt.Errorf("failed to send message, rate limited for %s", wait)
+	}
+
+	return 0, fmt.Errorf("failed to send message: %d %s", apiResponse.ErrorCode, apiResponse.Description)
+}
+
+// redact removes the token from the url included in errors of the http client, so it is not logged.
+func (t *TelegramNotifier) redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = strings.ReplaceAll(urlErr.URL, t.token, "<redacted>")
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package telegram_notifier
+
+type Option func(*TelegramNotifier)
+
+// WithAPIURL sends requests to url instead of DefaultAPIURL, such as a self-hosted Bot API server or a local stub.
+func WithAPIURL(url string) Option {
+	return func(t *TelegramNotifier) {
+		if url != "" {
+			t.apiURL = url
+		}
+	}
+}
+
+// WithMessageThreadID posts to a topic of a forum supergroup instead of its general topic.
+func WithMessageThreadID(threadID int) Option {
+	return func(t *TelegramNotifier) {
+		if threadID != 0 {
+			t.threadID = threadID
+		}
+	}
+}
+
+// WithParseMode formats messages with ParseModeHTML, the default, or ParseModeMarkdownV2.
+func WithParseMode(parseMode string) Option {
+	return func(t *TelegramNotifier) {
+		if parseMode != "" {
+			t.parseMode = parseMode
+		}
+	}
+}
+
+// WithSilentInfo sends events of info severity, such as a successful change, without a notification sound so only
+// warnings and errors alert anyone.
+func WithSilentInfo(silent bool) Option {
+	return func(t *TelegramNotifier) {
+		if silent {
+			t.silentInfo = silent
+		}
+	}
+}
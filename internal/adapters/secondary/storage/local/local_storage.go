@@ -5,11 +5,17 @@ import (
 	"encoding/json"
 	"net"
 	"os"
+	"strings"
 	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
 )
 
 const (
-	LastIpAddressFile = "last_known_ip_address"
+	LastIpAddressFile   = "last_known_ip_address"
+	LastIpv6AddressFile = "last_known_ipv6_address"
+	RecordsFile         = "records"
 )
 
 type LocalStorage struct {
@@ -20,45 +26,181 @@ func New(directory string) *LocalStorage {
 	return &LocalStorage{Directory: directory}
 }
 
-func (l *LocalStorage) GetLastKnownIPAddress(ctx context.Context) (net.IP, error) {
-	filename := l.Directory + "/" + LastIpAddressFile + ".json"
+func (l *LocalStorage) GetLastKnownIPAddress(ctx context.Context, family inet.Family) (net.IP, error) {
+	data, err := l.readLastKnownIPAddress(family)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.IPAddress, nil
+}
+
+// GetIPAddressObservedAt returns when the last known address was saved, which only happens when it changes.
+func (l *LocalStorage) GetIPAddressObservedAt(ctx context.Context, family inet.Family) (time.Time, error) {
+	data, err := l.readLastKnownIPAddress(family)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return data.CheckedAt, nil
+}
+
+func (l *LocalStorage) SaveIPAddress(ctx context.Context, family inet.Family, ip net.IP) error {
+	filename := l.lastKnownIPAddressFilename(family)
+
+	data := LastKnownIPAddressData{
+		IPAddress: ip,
+		CheckedAt: time.Now(),
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filename, jsonData, 0644)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (l *LocalStorage) readLastKnownIPAddress(family inet.Family) (LastKnownIPAddressData, error) {
+	filename := l.lastKnownIPAddressFilename(family)
+
+	var data LastKnownIPAddressData
 
 	_, err := os.Stat(filename)
 	if os.IsNotExist(err) {
-		return nil, nil
+		return data, nil
 	}
 
 	fileData, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, err
+		return data, err
 	}
 
-	var data LastKnownIPAddressData
 	err = json.Unmarshal(fileData, &data)
+	if err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// IPv4 keeps the original file name so state written by earlier versions is still read.
+func (l *LocalStorage) lastKnownIPAddressFilename(family inet.Family) string {
+	if family == inet.IPv6 {
+		return l.Directory + "/" + LastIpv6AddressFile + ".json"
+	}
+	return l.Directory + "/" + LastIpAddressFile + ".json"
+}
+
+func (l *LocalStorage) GetPublishedIPAddress(ctx context.Context, record gateway.RecordID, family inet.Family) (net.IP, error) {
+	records, err := l.readRecords()
 	if err != nil {
 		return nil, err
 	}
 
-	return data.IPAddress, nil
+	return records[recordKey(record, family)].PublishedIP, nil
 }
 
-func (l *LocalStorage) SaveIPAddress(ctx context.Context, ip net.IP) error {
-	filename := l.Directory + "/" + LastIpAddressFile + ".json"
+func (l *LocalStorage) SavePublishedIPAddress(ctx context.Context, record gateway.RecordID, family inet.Family, ip net.IP) error {
+	records, err := l.readRecords()
+	if err != nil {
+		return err
+	}
 
-	data := LastKnownIPAddressData{
-		IPAddress: ip,
-		CheckedAt: time.Now(),
+	key := recordKey(record, family)
+	state := records[key]
+	state.PublishedIP = ip
+	state.PublishedAt = time.Now()
+	records[key] = state
+
+	return l.writeRecords(records)
+}
+
+func (l *LocalStorage) GetLastReconciledAt(ctx context.Context, record gateway.RecordID, family inet.Family) (time.Time, error) {
+	records, err := l.readRecords()
+	if err != nil {
+		return time.Time{}, err
 	}
 
-	jsonData, err := json.Marshal(data)
+	return records[recordKey(record, family)].ReconciledAt, nil
+}
+
+func (l *LocalStorage) SaveLastReconciledAt(ctx context.Context, record gateway.RecordID, family inet.Family, at time.Time) error {
+	records, err := l.readRecords()
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(filename, jsonData, 0644)
+	key := recordKey(record, family)
+	state := records[key]
+	state.ReconciledAt = at
+	records[key] = state
+
+	return l.writeRecords(records)
+}
+
+func (l *LocalStorage) GetBackoffUntil(ctx context.Context, record gateway.RecordID, family inet.Family) (time.Time, error) {
+	records, err := l.readRecords()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return records[recordKey(record, family)].BackoffUntil, nil
+}
+
+func (l *LocalStorage) SaveBackoffUntil(ctx context.Context, record gateway.RecordID, family inet.Family, until time.Time) error {
+	records, err := l.readRecords()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	key := recordKey(record, family)
+	state := records[key]
+	state.BackoffUntil = until
+	records[key] = state
+
+	return l.writeRecords(records)
+}
+
+func (l *LocalStorage) readRecords() (RecordsData, error) {
+	filename := l.Directory + "/" + RecordsFile + ".json"
+
+	records := RecordsData{}
+
+	_, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+
+	fileData, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(fileData, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (l *LocalStorage) writeRecords(records RecordsData) error {
+	filename := l.Directory + "/" + RecordsFile + ".json"
+
+	jsonData, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, jsonData, 0644)
+}
+
+func recordKey(record gateway.RecordID, family inet.Family) string {
+	return strings.Join([]string{record.Provider, record.Zone, record.Name, family.RecordType()}, "/")
 }
@@ -9,3 +9,13 @@ type LastKnownIPAddressData struct {
 	IPAddress net.IP    `json:"ip_address"`
 	CheckedAt time.Time `json:"checked_at"`
 }
+
+type RecordStateData struct {
+	PublishedIP  net.IP    `json:"published_ip,omitempty"`
+	PublishedAt  time.Time `json:"published_at,omitempty"`
+	ReconciledAt time.Time `json:"reconciled_at,omitempty"`
+	BackoffUntil time.Time `json:"backoff_until,omitempty"`
+}
+
+// RecordsData is keyed by provider, zone, record name and record type, see recordKey.
+type RecordsData map[string]RecordStateData
@@ -3,8 +3,11 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"reflect"
+	"strconv"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
@@ -18,51 +21,175 @@ const (
 	RecordNameEnvVar     = "RECORD_NAME"
 	DiscordWebhookEnvVar = "DISCORD_WEBHOOK"
 	LocalStorageDirEnv   = "LOCAL_STORAGE_DIR"
+	DaemonEnvVar         = "DAEMON_MODE"
+	IntervalEnvVar       = "CHECK_INTERVAL"
+	JitterEnvVar         = "CHECK_JITTER"
+	TimeoutEnvVar        = "CHECK_TIMEOUT"
 )
 
 const (
-	NotifierTypeDiscord = "discord"
-)
-
-const (
-	DnsRecordTypeCloudflare = "cloudflare"
+	NotifierTypeDiscord  = "discord"
+	NotifierTypeSlack    = "slack"
+	NotifierTypeTelegram = "telegram"
+	NotifierTypeEmail    = "email"
 )
 
 type Notifier interface {
 	GetNotifierType() string
 }
 
+// DiscordNotifierConfig posts to a webhook. Timeout bounds each request, defaulting to 10s.
 type DiscordNotifierConfig struct {
 	Type       string `json:"type"`
 	WebhookUrl string `json:"webhookUrl"`
 	Username   string `json:"username"`
 	AvatarUrl  string `json:"avatarUrl"`
+	// PlainText sends events as plain message content instead of embeds.
+	PlainText bool     `json:"plainText"`
+	Timeout   Duration `json:"timeout"`
 }
 
 func (d DiscordNotifierConfig) GetNotifierType() string {
 	return d.Type
 }
 
+// SlackNotifierConfig posts to an incoming webhook when WebhookUrl is set, or with chat.postMessage to Channel when
+// Token is set instead. ApiUrl overrides the Slack Web API base url, such as for a local stand-in, and Timeout bounds
+// each request, defaulting to 10s.
+type SlackNotifierConfig struct {
+	Type       string   `json:"type"`
+	WebhookUrl string   `json:"webhookUrl"`
+	Token      string   `json:"token"`
+	Channel    string   `json:"channel"`
+	Username   string   `json:"username"`
+	IconUrl    string   `json:"iconUrl"`
+	ApiUrl     string   `json:"apiUrl"`
+	Timeout    Duration `json:"timeout"`
+}
+
+func (s SlackNotifierConfig) GetNotifierType() string {
+	return s.Type
+}
+
+// TelegramNotifierConfig sends as the bot of Token to ChatId, the numeric ID of a chat or the @username of a public
+// channel. SilentInfo sends events of info severity without a notification sound, and ApiUrl overrides the Bot API
+// base url, such as for a self-hosted Bot API server. Timeout bounds each request, defaulting to 10s.
+type TelegramNotifierConfig struct {
+	Type            string   `json:"type"`
+	Token           string   `json:"token"`
+	ChatId          string   `json:"chatId"`
+	MessageThreadId int      `json:"messageThreadId"`
+	ParseMode       string   `json:"parseMode"`
+	SilentInfo      bool     `json:"silentInfo"`
+	ApiUrl          string   `json:"apiUrl"`
+	Timeout         Duration `json:"timeout"`
+}
+
+func (t TelegramNotifierConfig) GetNotifierType() string {
+	return t.Type
+}
+
+// EmailNotifierConfig sends from From to every address of To through the SMTP server at Host. TLSMode is "starttls",
+// the default, "implicit" or "none", and a Port of 0 uses the default port of the mode. Authentication is only
+// attempted when Username is set, with AuthMechanism "plain", the default, or "login". Subject is a text/template
+// such as "[dynamic-ip-watcher] {{.Title}} on {{.Host}}".
+type EmailNotifierConfig struct {
+	Type               string   `json:"type"`
+	Host               string   `json:"host"`
+	Port               int      `json:"port"`
+	TLSMode            string   `json:"tlsMode"`
+	InsecureSkipVerify bool     `json:"insecureSkipVerify"`
+	Username           string   `json:"username"`
+	Password           string   `json:"password"`
+	AuthMechanism      string   `json:"authMechanism"`
+	From               string   `json:"from"`
+	To                 []string `json:"to"`
+	Subject            string   `json:"subject"`
+	Timeout            Duration `json:"timeout"`
+}
+
+func (e EmailNotifierConfig) GetNotifierType() string {
+	return e.Type
+}
+
 type NotifierConfig struct {
 	Type     string `json:"type"`
 	Endpoint string `json:"endpoint"`
 }
 
-type DNSRecordConfig struct {
-	Type       string `json:"type"`
-	APIKey     string `json:"apiKey"`
-	ZoneName   string `json:"zoneName"`
-	RecordName string `json:"recordName"`
-}
-
 type StorageConfig struct {
 	Directory string `json:"directory"`
 }
 
+// Duration is a time.Duration that is expressed as a string, such as "5m", in the configuration file.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5m\": %w", err)
+	}
+
+	if value == "" {
+		d.Duration = 0
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// WatcherConfig controls how often the address check runs. When Daemon is false, a single check is performed.
+type WatcherConfig struct {
+	Daemon   bool     `json:"daemon"`
+	Interval Duration `json:"interval"`
+	Jitter   Duration `json:"jitter"`
+	Timeout  Duration `json:"timeout"`
+}
+
+// ReconciliationConfig controls checking DNS records against the current address when it has not changed, so
+// records edited outside of this tool or left stale by a failed update are corrected. It is off unless Enabled is
+// set, and an Interval of zero then checks on every run.
+type ReconciliationConfig struct {
+	Enabled  bool     `json:"enabled"`
+	Interval Duration `json:"interval"`
+}
+
+// IPValidationConfig restricts which addresses are published. Addresses that are not publicly routable are always
+// rejected unless within AllowedPrefixes; when set, an address must be within one of AllowedPrefixes and announced by
+// one of AllowedASNs, looked up with ip-api.
+type IPValidationConfig struct {
+	AllowedPrefixes []string `json:"allowedPrefixes"`
+	AllowedASNs     []uint32 `json:"allowedAsns"`
+}
+
+// EnrichmentConfig adds the ISP, location and kind of network of the previous and current address to change events,
+// looked up with ip-api. APIKey is an ip-api Pro key, also used for the lookups of IPValidationConfig.AllowedASNs.
+type EnrichmentConfig struct {
+	Enabled bool   `json:"enabled"`
+	APIKey  string `json:"apiKey"`
+}
+
 type Config struct {
-	DNSRecord DNSRecordConfig `json:"dnsRecord"`
-	Storage   StorageConfig   `json:"storage"`
-	Notifiers []Notifier      `json:"notifiers"`
+	DNSRecords     []DNSRecord          `json:"dnsRecords"`
+	IPRetriever    IPRetriever          `json:"ipRetriever"`
+	IPValidation   IPValidationConfig   `json:"ipValidation"`
+	Enrichment     EnrichmentConfig     `json:"enrichment"`
+	Storage        StorageConfig        `json:"storage"`
+	Watcher        WatcherConfig        `json:"watcher"`
+	Reconciliation ReconciliationConfig `json:"reconciliation"`
+	Notifiers      []Notifier           `json:"notifiers"`
 }
 
 func getEnvOrDefault(envVar, defaultValue string) string {
@@ -74,6 +201,34 @@ func getEnvOrDefault(envVar, defaultValue string) string {
 	return value
 }
 
+func getDurationEnvOrDefault(envVar string, defaultValue Duration) (Duration, error) {
+	value := os.Getenv(envVar)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue, fmt.Errorf("invalid duration in %s: %w", envVar, err)
+	}
+
+	return Duration{parsed}, nil
+}
+
+func getBoolEnvOrDefault(envVar string, defaultValue bool) (bool, error) {
+	value := os.Getenv(envVar)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue, fmt.Errorf("invalid boolean in %s: %w", envVar, err)
+	}
+
+	return parsed, nil
+}
+
 // check if a --config-path arg was passed in,
 // if not, check if the CONFIG_PATH env var is set
 // if not, use the default path
@@ -93,6 +248,17 @@ func determineConfigPath() string {
 	return value
 }
 
+// check if a --daemon arg was passed in
+func daemonFlagPassed() bool {
+	for _, arg := range os.Args[1:] {
+		if arg == "--daemon" {
+			return true
+		}
+	}
+
+	return false
+}
+
 func loadConfigFromFile(cfg *Config, path string) error {
 	file, err := os.Open(path)
 	if err != nil {
@@ -102,9 +268,15 @@ func loadConfigFromFile(cfg *Config, path string) error {
 	defer file.Close()
 
 	var rawConfig struct {
-		DNSRecord DNSRecordConfig   `json:"dnsRecord"`
-		Storage   StorageConfig     `json:"storage"`
-		Notifiers []json.RawMessage `json:"notifiers"`
+		DNSRecord      json.RawMessage      `json:"dnsRecord"`
+		DNSRecords     []json.RawMessage    `json:"dnsRecords"`
+		IPRetriever    json.RawMessage      `json:"ipRetriever"`
+		IPValidation   IPValidationConfig   `json:"ipValidation"`
+		Enrichment     EnrichmentConfig     `json:"enrichment"`
+		Storage        StorageConfig        `json:"storage"`
+		Watcher        WatcherConfig        `json:"watcher"`
+		Reconciliation ReconciliationConfig `json:"reconciliation"`
+		Notifiers      []json.RawMessage    `json:"notifiers"`
 	}
 
 	if err := json.NewDecoder(file).Decode(&rawConfig); err != nil {
@@ -115,8 +287,48 @@ func loadConfigFromFile(cfg *Config, path string) error {
 		rawConfig.Storage.Directory = DefaultStorageDir
 	}
 
-	cfg.DNSRecord = rawConfig.DNSRecord
+	// the single dnsRecord object is still accepted for configurations written before dnsRecords existed
+	rawDNSRecords := rawConfig.DNSRecords
+	if len(rawConfig.DNSRecord) > 0 {
+		rawDNSRecords = append([]json.RawMessage{rawConfig.DNSRecord}, rawDNSRecords...)
+	}
+
+	var dnsRecords []DNSRecord
+	for _, rawDNSRecord := range rawDNSRecords {
+		dnsRecord, err := parseDNSRecord(rawDNSRecord)
+		if err != nil {
+			return err
+		}
+		if dnsRecord != nil {
+			dnsRecords = append(dnsRecords, dnsRecord)
+		}
+	}
+
+	// ip-api was the only IP source before ipRetriever existed
+	cfg.IPRetriever = &IPAPIIPRetrieverConfig{IPRetrieverConfig: IPRetrieverConfig{Type: IPRetrieverTypeIPAPI, Name: IPRetrieverTypeIPAPI}}
+	if len(rawConfig.IPRetriever) > 0 {
+		ipRetriever, err := parseIPRetriever(rawConfig.IPRetriever)
+		if err != nil {
+			return err
+		}
+		cfg.IPRetriever = ipRetriever
+	}
+
+	// publishing IPv6 would fail on every run, so refuse to start instead
+	if !supportsIPv6(cfg.IPRetriever) {
+		for _, dnsRecord := range dnsRecords {
+			if recordCfg := dnsRecord.GetDNSRecordConfig(); recordCfg.AddressFamily != AddressFamilyIPv4 {
+				return fmt.Errorf("DNS record %s publishes %s addresses, but the %s IP retriever cannot return an IPv6 address", recordCfg.RecordName, recordCfg.AddressFamily, cfg.IPRetriever.GetIPRetrieverConfig().Name)
+			}
+		}
+	}
+
+	cfg.DNSRecords = dnsRecords
+	cfg.IPValidation = rawConfig.IPValidation
+	cfg.Enrichment = rawConfig.Enrichment
 	cfg.Storage = rawConfig.Storage
+	cfg.Watcher = rawConfig.Watcher
+	cfg.Reconciliation = rawConfig.Reconciliation
 
 	for _, rawNotifier := range rawConfig.Notifiers {
 		var base struct {
@@ -135,6 +347,27 @@ func loadConfigFromFile(cfg *Config, path string) error {
 			}
 			replaceFilePaths(&discordConfig)
 			notifier = discordConfig
+		case NotifierTypeSlack:
+			var slackConfig SlackNotifierConfig
+			if err := json.Unmarshal(rawNotifier, &slackConfig); err != nil {
+				return err
+			}
+			replaceFilePaths(&slackConfig)
+			notifier = slackConfig
+		case NotifierTypeTelegram:
+			var telegramConfig TelegramNotifierConfig
+			if err := json.Unmarshal(rawNotifier, &telegramConfig); err != nil {
+				return err
+			}
+			replaceFilePaths(&telegramConfig)
+			notifier = telegramConfig
+		case NotifierTypeEmail:
+			var emailConfig EmailNotifierConfig
+			if err := json.Unmarshal(rawNotifier, &emailConfig); err != nil {
+				return err
+			}
+			replaceFilePaths(&emailConfig)
+			notifier = emailConfig
 		default:
 			return errors.New("unknown notifier type: " + base.Type)
 		}
@@ -199,12 +432,33 @@ func tryReadFile(path string) (string, error) {
 	return string(content), nil
 }
 
-func setConfigFromEnv(cfg *Config) {
-	cfg.DNSRecord.ZoneName = getEnvOrDefault(ZoneIDEnvVar, cfg.DNSRecord.ZoneName)
-	cfg.DNSRecord.RecordName = getEnvOrDefault(RecordNameEnvVar, cfg.DNSRecord.RecordName)
+func setConfigFromEnv(cfg *Config) error {
+	var err error
+
+	if len(cfg.DNSRecords) > 0 {
+		dnsRecord := cfg.DNSRecords[0].GetDNSRecordConfig()
+		dnsRecord.ZoneName = getEnvOrDefault(ZoneIDEnvVar, dnsRecord.ZoneName)
+		dnsRecord.RecordName = getEnvOrDefault(RecordNameEnvVar, dnsRecord.RecordName)
+	}
 
 	cfg.Storage.Directory = getEnvOrDefault(LocalStorageDirEnv, cfg.Storage.Directory)
 
+	if cfg.Watcher.Daemon, err = getBoolEnvOrDefault(DaemonEnvVar, cfg.Watcher.Daemon); err != nil {
+		return err
+	}
+	if cfg.Watcher.Interval, err = getDurationEnvOrDefault(IntervalEnvVar, cfg.Watcher.Interval); err != nil {
+		return err
+	}
+	if cfg.Watcher.Jitter, err = getDurationEnvOrDefault(JitterEnvVar, cfg.Watcher.Jitter); err != nil {
+		return err
+	}
+	if cfg.Watcher.Timeout, err = getDurationEnvOrDefault(TimeoutEnvVar, cfg.Watcher.Timeout); err != nil {
+		return err
+	}
+	if daemonFlagPassed() {
+		cfg.Watcher.Daemon = true
+	}
+
 	if os.Getenv(DiscordWebhookEnvVar) != "" {
 		if len(cfg.Notifiers) == 0 {
 			cfg.Notifiers = make([]Notifier, 0)
@@ -217,6 +471,8 @@ func setConfigFromEnv(cfg *Config) {
 			})
 		}
 	}
+
+	return nil
 }
 
 func setDiscordWebhookValue(cfg *Config, value string) bool {
@@ -245,7 +501,11 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
-	setConfigFromEnv(cfg)
+	err = setConfigFromEnv(cfg)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load configuration from environment")
+		return nil, err
+	}
 
 	return cfg, nil
 }
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+const (
+	DnsRecordTypeNone       = "none"
+	DnsRecordTypeCloudflare = "cloudflare"
+	DnsRecordTypeRoute53    = "route53"
+	DnsRecordTypeRFC2136    = "rfc2136"
+	DnsRecordTypeHTTP       = "http"
+	DnsRecordTypeDynDNS2    = "dyndns2"
+	DnsRecordTypeExec       = "exec"
+)
+
+const (
+	DuplicatePolicyFail             = "fail"
+	DuplicatePolicyUpdateAll        = "update-all"
+	DuplicatePolicyDeleteDuplicates = "delete-duplicates"
+)
+
+const (
+	AddressFamilyIPv4 = "ipv4"
+	AddressFamilyIPv6 = "ipv6"
+	AddressFamilyBoth = "both"
+)
+
+// DNSRecord is implemented by the configuration of every DNS provider by embedding DNSRecordConfig.
+type DNSRecord interface {
+	GetDNSRecordConfig() *DNSRecordConfig
+}
+
+// DNSRecordConfig holds the settings shared by every DNS provider.
+type DNSRecordConfig struct {
+	Type            string `json:"type"`
+	ZoneName        string `json:"zoneName"`
+	RecordName      string `json:"recordName"`
+	AddressFamily   string `json:"addressFamily"`
+	CreateIfMissing bool   `json:"createIfMissing"`
+	DuplicatePolicy string `json:"duplicatePolicy"`
+}
+
+func (d *DNSRecordConfig) GetDNSRecordConfig() *DNSRecordConfig {
+	return d
+}
+
+func (d *DNSRecordConfig) setDefaults() error {
+	switch d.AddressFamily {
+	case "":
+		d.AddressFamily = AddressFamilyIPv4
+	case AddressFamilyIPv4, AddressFamilyIPv6, AddressFamilyBoth:
+	default:
+		return errors.New("unknown address family: " + d.AddressFamily)
+	}
+
+	switch d.DuplicatePolicy {
+	case "":
+		d.DuplicatePolicy = DuplicatePolicyFail
+	case DuplicatePolicyFail, DuplicatePolicyUpdateAll, DuplicatePolicyDeleteDuplicates:
+	default:
+		return errors.New("unknown duplicate policy: " + d.DuplicatePolicy)
+	}
+
+	return nil
+}
+
+type CloudflareDNSRecordConfig struct {
+	DNSRecordConfig
+	APIKey string `json:"apiKey"`
+}
+
+// Route53DNSRecordConfig falls back to the default AWS credential chain when no access key is set. ZoneName is used
+// to look up the hosted zone unless HostedZoneID is set.
+type Route53DNSRecordConfig struct {
+	DNSRecordConfig
+	HostedZoneID    string   `json:"hostedZoneId"`
+	AccessKeyID     string   `json:"accessKeyId"`
+	SecretAccessKey string   `json:"secretAccessKey"`
+	Region          string   `json:"region"`
+	Endpoint        string   `json:"endpoint"`
+	TTL             int64    `json:"ttl"`
+	ChangeTimeout   Duration `json:"changeTimeout"`
+}
+
+// RFC2136DNSRecordConfig updates records on an authoritative server, such as BIND or Knot, using DNS UPDATE. Messages
+// are signed when TSIGKeyName is set; TSIGSecret is the base64 key material.
+type RFC2136DNSRecordConfig struct {
+	DNSRecordConfig
+	Server        string   `json:"server"`
+	Transport     string   `json:"transport"`
+	TSIGKeyName   string   `json:"tsigKeyName"`
+	TSIGSecret    string   `json:"tsigSecret"`
+	TSIGAlgorithm string   `json:"tsigAlgorithm"`
+	TTL           uint32   `json:"ttl"`
+	Timeout       Duration `json:"timeout"`
+}
+
+// HTTPDNSRecordConfig publishes with a templated HTTP request, for dynamic DNS services such as DuckDNS or dynv6.
+// URL, Body and Headers values are Go templates given .IP, .PreviousIP, .RecordName, .RecordType and .Family.
+type HTTPDNSRecordConfig struct {
+	DNSRecordConfig
+	Method             string            `json:"method"`
+	URL                string            `json:"url"`
+	Headers            map[string]string `json:"headers"`
+	Body               string            `json:"body"`
+	SuccessStatusCodes []int             `json:"successStatusCodes"`
+	SuccessBodyRegex   string            `json:"successBodyRegex"`
+	SuccessJSONPath    string            `json:"successJsonPath"`
+	SuccessJSONValue   string            `json:"successJsonValue"`
+	Timeout            Duration          `json:"timeout"`
+}
+
+// DynDNS2DNSRecordConfig publishes with the dyndns2 protocol, to Server such as https://dynupdate.no-ip.com.
+type DynDNS2DNSRecordConfig struct {
+	DNSRecordConfig
+	Server    string   `json:"server"`
+	Username  string   `json:"username"`
+	Password  string   `json:"password"`
+	UserAgent string   `json:"userAgent"`
+	Timeout   Duration `json:"timeout"`
+}
+
+// ExecDNSRecordConfig publishes by running Command, whose arguments are Go templates given .IP, .PreviousIP,
+// .RecordName, .RecordType and .Family. Only the variables named in AllowedEnv are passed through from this process,
+// defaulting to PATH, HOME, LANG, LC_ALL and TZ, and Env sets more.
+type ExecDNSRecordConfig struct {
+	DNSRecordConfig
+	Command    []string          `json:"command"`
+	Env        map[string]string `json:"env"`
+	AllowedEnv []string          `json:"allowedEnv"`
+	Timeout    Duration          `json:"timeout"`
+}
+
+// parseDNSRecord decodes a DNS record by its type. A nil record is returned for records with no or the none type.
+func parseDNSRecord(rawDNSRecord json.RawMessage) (DNSRecord, error) {
+	var base struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(rawDNSRecord, &base); err != nil {
+		return nil, err
+	}
+
+	var dnsRecord DNSRecord
+	switch base.Type {
+	case "", DnsRecordTypeNone:
+		return nil, nil
+	case DnsRecordTypeCloudflare:
+		dnsRecord = &CloudflareDNSRecordConfig{}
+	case DnsRecordTypeRoute53:
+		dnsRecord = &Route53DNSRecordConfig{}
+	case DnsRecordTypeRFC2136:
+		dnsRecord = &RFC2136DNSRecordConfig{}
+	case DnsRecordTypeHTTP:
+		dnsRecord = &HTTPDNSRecordConfig{}
+	case DnsRecordTypeDynDNS2:
+		dnsRecord = &DynDNS2DNSRecordConfig{}
+	case DnsRecordTypeExec:
+		dnsRecord = &ExecDNSRecordConfig{}
+	default:
+		return nil, errors.New("unknown DNS record type: " + base.Type)
+	}
+
+	if err := json.Unmarshal(rawDNSRecord, dnsRecord); err != nil {
+		return nil, err
+	}
+
+	if err := dnsRecord.GetDNSRecordConfig().setDefaults(); err != nil {
+		return nil, err
+	}
+
+	if err := replaceFilePaths(dnsRecord); err != nil {
+		return nil, err
+	}
+
+	return dnsRecord, nil
+}
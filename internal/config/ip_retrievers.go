@@ -0,0 +1,217 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+const (
+	IPRetrieverTypeIPAPI     = "ipapi"
+	IPRetrieverTypeIpify     = "ipify"
+	IPRetrieverTypeIcanhazip = "icanhazip"
+	IPRetrieverTypeConsensus = "consensus"
+	IPRetrieverTypeDNS       = "dns"
+	IPRetrieverTypeSTUN      = "stun"
+	IPRetrieverTypeGateway   = "gateway"
+	IPRetrieverTypeInterface = "interface"
+	IPRetrieverTypeHTTP      = "http"
+	IPRetrieverTypeExec      = "exec"
+)
+
+const (
+	DNSIPProviderOpenDNS    = "opendns"
+	DNSIPProviderCloudflare = "cloudflare"
+	DNSIPProviderGoogle     = "google"
+)
+
+// IPRetriever is implemented by the configuration of every IP source by embedding IPRetrieverConfig.
+type IPRetriever interface {
+	GetIPRetrieverConfig() *IPRetrieverConfig
+}
+
+// IPRetrieverConfig holds the settings shared by every IP source. Name identifies the source in logs and
+// notifications and defaults to Type. Sources with no settings of their own, such as ipify, use it directly.
+type IPRetrieverConfig struct {
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Timeout Duration `json:"timeout"`
+}
+
+func (i *IPRetrieverConfig) GetIPRetrieverConfig() *IPRetrieverConfig {
+	return i
+}
+
+// IPAPIIPRetrieverConfig reads the address from ip-api.com, using the Pro endpoint over HTTPS when APIKey is set.
+type IPAPIIPRetrieverConfig struct {
+	IPRetrieverConfig
+	APIKey string `json:"apiKey"`
+}
+
+// ConsensusIPRetrieverConfig queries Sources, in order of preference, and uses an address once Quorum of them
+// agree. Quorum defaults to a majority of the sources and Timeout applies to each source.
+type ConsensusIPRetrieverConfig struct {
+	IPRetrieverConfig
+	Quorum  int           `json:"quorum"`
+	Sources []IPRetriever `json:"-"`
+}
+
+// DNSIPRetrieverConfig asks resolvers which address a query came from. Provider is one of opendns, cloudflare or
+// google, defaulting to opendns, and the other settings override it, such as to use a local resolver.
+type DNSIPRetrieverConfig struct {
+	IPRetrieverConfig
+	Provider    string   `json:"provider"`
+	IPv4Servers []string `json:"ipv4Servers"`
+	IPv6Servers []string `json:"ipv6Servers"`
+	QueryName   string   `json:"queryName"`
+	QueryType   string   `json:"queryType"`
+	QueryClass  string   `json:"queryClass"`
+}
+
+// STUNIPRetrieverConfig sends STUN Binding requests to Servers, given as host or host:port, falling back to public
+// servers when none are set. The NAT mapping behaviour is tested too unless DisableNATDetection is set.
+type STUNIPRetrieverConfig struct {
+	IPRetrieverConfig
+	Servers             []string `json:"servers"`
+	DisableNATDetection bool     `json:"disableNatDetection"`
+}
+
+// GatewayIPRetrieverConfig asks the local router for its WAN address, trying Methods in order from upnp, natpmp and
+// pcp. GatewayAddress is the router used for natpmp and pcp, defaulting to the default gateway, and SSDPAddress is
+// where UPnP discovery is sent, defaulting to the SSDP multicast group.
+type GatewayIPRetrieverConfig struct {
+	IPRetrieverConfig
+	Methods        []string `json:"methods"`
+	GatewayAddress string   `json:"gatewayAddress"`
+	SSDPAddress    string   `json:"ssdpAddress"`
+}
+
+// InterfaceIPRetrieverConfig reads the address from Interface on this host, or every interface that is up when it is
+// empty. Private, temporary and deprecated addresses are skipped unless allowed, and Prefixes and ExcludePrefixes
+// limit the addresses used by CIDR. Private addresses are only published when within
+// IPValidationConfig.AllowedPrefixes.
+type InterfaceIPRetrieverConfig struct {
+	IPRetrieverConfig
+	Interface         string   `json:"interface"`
+	AllowPrivate      bool     `json:"allowPrivate"`
+	IncludeTemporary  bool     `json:"includeTemporary"`
+	IncludeDeprecated bool     `json:"includeDeprecated"`
+	Prefixes          []string `json:"prefixes"`
+	ExcludePrefixes   []string `json:"excludePrefixes"`
+}
+
+// HTTPIPRetrieverConfig reads the address from IPv4URL and IPv6URL, leaving a URL empty when the family is not
+// available. The whole body must be the address unless Regex or JSONPath is set to extract it from the response.
+type HTTPIPRetrieverConfig struct {
+	IPRetrieverConfig
+	IPv4URL  string            `json:"ipv4Url"`
+	IPv6URL  string            `json:"ipv6Url"`
+	Headers  map[string]string `json:"headers"`
+	Regex    string            `json:"regex"`
+	JSONPath string            `json:"jsonPath"`
+}
+
+// ExecIPRetrieverConfig runs Command, which prints the address of the family named in the FAMILY variable. Only the
+// variables named in AllowedEnv are passed through from this process, defaulting to PATH, HOME, LANG, LC_ALL and TZ,
+// and Env sets more.
+type ExecIPRetrieverConfig struct {
+	IPRetrieverConfig
+	Command    []string          `json:"command"`
+	Env        map[string]string `json:"env"`
+	AllowedEnv []string          `json:"allowedEnv"`
+}
+
+func parseIPRetriever(rawIPRetriever json.RawMessage) (IPRetriever, error) {
+	var base struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(rawIPRetriever, &base); err != nil {
+		return nil, err
+	}
+
+	var ipRetriever IPRetriever
+	switch base.Type {
+	case IPRetrieverTypeIPAPI:
+		ipRetriever = &IPAPIIPRetrieverConfig{}
+	case IPRetrieverTypeIpify, IPRetrieverTypeIcanhazip:
+		ipRetriever = &IPRetrieverConfig{}
+	case IPRetrieverTypeConsensus:
+		ipRetriever = &ConsensusIPRetrieverConfig{}
+	case IPRetrieverTypeDNS:
+		ipRetriever = &DNSIPRetrieverConfig{}
+	case IPRetrieverTypeSTUN:
+		ipRetriever = &STUNIPRetrieverConfig{}
+	case IPRetrieverTypeGateway:
+		ipRetriever = &GatewayIPRetrieverConfig{}
+	case IPRetrieverTypeInterface:
+		ipRetriever = &InterfaceIPRetrieverConfig{}
+	case IPRetrieverTypeHTTP:
+		ipRetriever = &HTTPIPRetrieverConfig{}
+	case IPRetrieverTypeExec:
+		ipRetriever = &ExecIPRetrieverConfig{}
+	default:
+		return nil, errors.New("unknown IP retriever type: " + base.Type)
+	}
+
+	if err := json.Unmarshal(rawIPRetriever, ipRetriever); err != nil {
+		return nil, err
+	}
+
+	if ipRetriever.GetIPRetrieverConfig().Name == "" {
+		ipRetriever.GetIPRetrieverConfig().Name = base.Type
+	}
+
+	if dnsRetriever, ok := ipRetriever.(*DNSIPRetrieverConfig); ok && dnsRetriever.Provider == "" {
+		dnsRetriever.Provider = DNSIPProviderOpenDNS
+	}
+
+	if consensus, ok := ipRetriever.(*ConsensusIPRetrieverConfig); ok {
+		var rawSources struct {
+			Sources []json.RawMessage `json:"sources"`
+		}
+		if err := json.Unmarshal(rawIPRetriever, &rawSources); err != nil {
+			return nil, err
+		}
+
+		for _, rawSource := range rawSources.Sources {
+			source, err := parseIPRetriever(rawSource)
+			if err != nil {
+				return nil, err
+			}
+			consensus.Sources = append(consensus.Sources, source)
+		}
+	}
+
+	if err := replaceFilePaths(ipRetriever); err != nil {
+		return nil, err
+	}
+
+	return ipRetriever, nil
+}
+
+// supportsIPv6 reports whether the IP source can return an IPv6 address. ip-api and the router only tell the IPv4
+// address, an http source needs an IPv6 URL, and a consensus needs a quorum of sources that can.
+func supportsIPv6(ipRetriever IPRetriever) bool {
+	switch ipRetriever := ipRetriever.(type) {
+	case *HTTPIPRetrieverConfig:
+		return ipRetriever.IPv6URL != ""
+	case *ConsensusIPRetrieverConfig:
+		quorum := ipRetriever.Quorum
+		if quorum <= 0 {
+			quorum = len(ipRetriever.Sources)/2 + 1
+		}
+		supported := 0
+		for _, source := range ipRetriever.Sources {
+			if supportsIPv6(source) {
+				supported++
+			}
+		}
+		return supported >= quorum
+	}
+
+	switch ipRetriever.GetIPRetrieverConfig().Type {
+	case IPRetrieverTypeIPAPI, IPRetrieverTypeGateway:
+		return false
+	default:
+		return true
+	}
+}
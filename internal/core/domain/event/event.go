@@ -1,37 +1,541 @@
 package event
 
-import "fmt"
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
 
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+)
+
+// Kind identifies the type of an event, such as in its JSON encoding.
+type Kind string
+
+const (
+	KindAddressChanged   Kind = "address_changed"
+	KindUpdateFailed     Kind = "update_failed"
+	KindRecordCreated    Kind = "record_created"
+	KindDriftCorrected   Kind = "drift_corrected"
+	KindSourcesDisagree  Kind = "sources_disagree"
+	KindGatewayBehindNAT Kind = "gateway_behind_nat"
+)
+
+// Severity tells how urgently an event needs attention.
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Color is the RGB color notifiers highlight an event of the severity with: green for info, yellow for a warning
+// and red for an error.
+func (s Severity) Color() int {
+	switch s {
+	case SeverityError:
+		return 0xE74C3C
+	case SeverityWarning:
+		return 0xF1C40F
+	default:
+		return 0x2ECC71
+	}
+}
+
+// Event is something that happened while watching the address, to be reported to the notifiers. Every event carries
+// Metadata along with its own fields. Title names it in a few words for notifiers that show a heading, and AsMessage
+// renders it as plain text for notifiers with no richer format.
 type Event interface {
+	Kind() Kind
+	Severity() Severity
+	Meta() *Metadata
+	Title() string
 	AsMessage() string
 }
 
+// Metadata is common to every event. Timestamp is set when the event is created, while Host and RunID are set by the
+// service when the event is sent.
+type Metadata struct {
+	Timestamp time.Time
+	Host      string
+	// RunID is shared by every event raised during the same check of the address.
+	RunID string
+}
+
+func newMetadata() Metadata {
+	return Metadata{Timestamp: time.Now()}
+}
+
+func (m *Metadata) Meta() *Metadata {
+	return m
+}
+
+// Origin names where the event came from, such as "nas • run 1a2b3c", or is empty when neither the host nor the run
+// is known.
+func (m *Metadata) Origin() string {
+	var parts []string
+	if m.Host != "" {
+		parts = append(parts, m.Host)
+	}
+	if m.RunID != "" {
+		parts = append(parts, "run "+m.RunID)
+	}
+	return strings.Join(parts, " • ")
+}
+
+// Footer adds the time of the event in UTC to its Origin, for notifiers with no timestamp of their own.
+func (m *Metadata) Footer() string {
+	var parts []string
+	if origin := m.Origin(); origin != "" {
+		parts = append(parts, origin)
+	}
+	if !m.Timestamp.IsZero() {
+		parts = append(parts, m.Timestamp.UTC().Format("2006-01-02 15:04:05 MST"))
+	}
+	return strings.Join(parts, " • ")
+}
+
+// Operation is the step of handling an address that failed.
+type Operation string
+
+const (
+	OperationReadLastKnownAddress Operation = "read_last_known_address"
+	OperationRetrieveAddress      Operation = "retrieve_address"
+	OperationValidateAddress      Operation = "validate_address"
+	OperationStoreAddress         Operation = "store_address"
+	OperationReadRecord           Operation = "read_record"
+	OperationCorrectRecord        Operation = "correct_record"
+)
+
+// FailedUpdateEvent reports a step that failed, preventing the address of Family from being handled. RecordName is
+// set when the failure concerns a single DNS record.
 type FailedUpdateEvent struct {
-	Message string
-	Error   error
+	Metadata   `json:"-"`
+	Family     inet.Family `json:"family"`
+	Operation  Operation   `json:"operation"`
+	RecordName string      `json:"recordName,omitempty"`
+	Error      error       `json:"-"`
 }
 
-func NewFailedUpdateEvent(message string, err error) *FailedUpdateEvent {
+func NewFailedUpdateEvent(family inet.Family, operation Operation, err error) *FailedUpdateEvent {
 	return &FailedUpdateEvent{
-		Message: message,
-		Error:   err,
+		Metadata:  newMetadata(),
+		Family:    family,
+		Operation: operation,
+		Error:     err,
+	}
+}
+
+func NewFailedRecordUpdateEvent(recordName string, family inet.Family, operation Operation, err error) *FailedUpdateEvent {
+	e := NewFailedUpdateEvent(family, operation, err)
+	e.RecordName = recordName
+	return e
+}
+
+func (e FailedUpdateEvent) Kind() Kind {
+	return KindUpdateFailed
+}
+
+func (e FailedUpdateEvent) Severity() Severity {
+	return SeverityError
+}
+
+// Title describes the step that failed, such as "Failed to determine current IPv4 address".
+func (e FailedUpdateEvent) Title() string {
+	switch e.Operation {
+	case OperationReadLastKnownAddress:
+		return fmt.Sprintf("Failed to determine the last known %s address", e.Family)
+	case OperationRetrieveAddress:
+		return fmt.Sprintf("Failed to determine current %s address", e.Family)
+	case OperationValidateAddress:
+		return fmt.Sprintf("Refusing to publish the current %s address", e.Family)
+	case OperationStoreAddress:
+		return fmt.Sprintf("Failed to store new %s address", e.Family)
+	case OperationReadRecord:
+		return fmt.Sprintf("Failed to read DNS %s Record %s", e.Family.RecordType(), e.RecordName)
+	case OperationCorrectRecord:
+		return fmt.Sprintf("Failed to correct drifted DNS %s Record %s", e.Family.RecordType(), e.RecordName)
+	default:
+		return fmt.Sprintf("Failed to handle %s address", e.Family)
 	}
 }
 
 func (e FailedUpdateEvent) AsMessage() string {
-	return fmt.Sprintf("%s: %s", e.Message, e.Error)
+	return fmt.Sprintf("%s: %s", e.Title(), e.Error)
+}
+
+// AddressChange describes the public address of one family moving from PreviousIP to CurrentIP. NATMapping is set
+// when the IP source could tell how the address is translated. PreviousInfo and CurrentInfo describe the network of
+// each address when enrichment is enabled and the lookup succeeded, and are nil otherwise. PreviousSince is when
+// PreviousIP was first observed, if known.
+type AddressChange struct {
+	Family        inet.Family     `json:"family"`
+	PreviousIP    net.IP          `json:"previousIp,omitempty"`
+	PreviousSince *time.Time      `json:"previousSince,omitempty"`
+	CurrentIP     net.IP          `json:"currentIp"`
+	NATMapping    inet.NATMapping `json:"natMapping,omitempty"`
+	PreviousInfo  *inet.IPInfo    `json:"previousInfo,omitempty"`
+	CurrentInfo   *inet.IPInfo    `json:"currentInfo,omitempty"`
+}
+
+// describeNetwork summarizes how the network behind the address changed, or returns an empty string when unknown.
+func (c AddressChange) describeNetwork() string {
+	if c.CurrentInfo == nil {
+		return ""
+	}
+
+	var sentences []string
+	current := c.CurrentInfo
+	switch {
+	case c.PreviousInfo != nil && c.PreviousInfo.Network() != current.Network():
+		sentences = append(sentences, fmt.Sprintf("ISP changed from %s to %s.", c.PreviousInfo.Network(), current.Network()))
+	case c.PreviousInfo == nil && current.Network() != "":
+		sentences = append(sentences, fmt.Sprintf("New address belongs to %s.", current.Network()))
+	}
+
+	if location := current.Location(); location != "" && (c.PreviousInfo == nil || c.PreviousInfo.Location() != location) {
+		sentences = append(sentences, fmt.Sprintf("Located in %s.", location))
+	}
+
+	if kinds := current.Kinds(); len(kinds) > 0 {
+		sentences = append(sentences, fmt.Sprintf("New address is flagged as %s.", strings.Join(kinds, " and ")))
+	}
+
+	return strings.Join(sentences, " ")
 }
 
+// NetworkChange names the network of CurrentIP, preceded by that of PreviousIP when it differs, such as
+// "Comcast → Verizon". It is empty when the network of CurrentIP is unknown.
+func (c AddressChange) NetworkChange() string {
+	if c.CurrentInfo == nil || c.CurrentInfo.Network() == "" {
+		return ""
+	}
+
+	current := c.CurrentInfo.Network()
+	if c.PreviousInfo != nil && c.PreviousInfo.Network() != "" && c.PreviousInfo.Network() != current {
+		return fmt.Sprintf("%s → %s", c.PreviousInfo.Network(), current)
+	}
+	return current
+}
+
+// HeldFor returns how long PreviousIP was held until at, or zero when unknown.
+func (c AddressChange) HeldFor(at time.Time) time.Duration {
+	if c.PreviousSince == nil || c.PreviousSince.After(at) {
+		return 0
+	}
+	return at.Sub(*c.PreviousSince)
+}
+
+// FormatDuration renders d in its two largest units, such as "3d 4h" or "12m 5s", for display in notifications.
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+
+	var parts []string
+	for _, unit := range units {
+		if len(parts) == 2 {
+			break
+		}
+		count := d / unit.size
+		if count == 0 && len(parts) == 0 {
+			continue
+		}
+		d -= count * unit.size
+		if count > 0 {
+			parts = append(parts, fmt.Sprintf("%d%s", count, unit.suffix))
+		} else {
+			break
+		}
+	}
+
+	if len(parts) == 0 {
+		return "0s"
+	}
+	return strings.Join(parts, " ")
+}
+
+// Truncate shortens s to at most limit characters, marking the cut with an ellipsis, to fit the length a notifier
+// allows.
+func Truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// AsMessage describes the change in a single line.
+func (c AddressChange) AsMessage() string {
+	line := fmt.Sprintf("%s address changed from %s to %s.", c.Family, c.PreviousIP, c.CurrentIP)
+	if c.NATMapping != "" {
+		line += fmt.Sprintf(" NAT mapping is %s.", c.NATMapping)
+	}
+	if network := c.describeNetwork(); network != "" {
+		line += " " + network
+	}
+	return line
+}
+
+// RecordResult is the outcome of publishing an address to a single DNS record. Error is nil on success, and
+// Created is set when the record did not exist and was created.
+type RecordResult struct {
+	RecordName string      `json:"recordName"`
+	Family     inet.Family `json:"family"`
+	Created    bool        `json:"created"`
+	Error      error       `json:"-"`
+}
+
+// AsMessage describes the outcome in a single line.
+func (r RecordResult) AsMessage() string {
+	switch {
+	case r.Error != nil:
+		return fmt.Sprintf("Failed to update DNS %s Record %s: %s", r.Family.RecordType(), r.RecordName, r.Error)
+	case r.Created:
+		return fmt.Sprintf("DNS %s Record %s created with new address.", r.Family.RecordType(), r.RecordName)
+	default:
+		return fmt.Sprintf("DNS %s Record %s updated with new address.", r.Family.RecordType(), r.RecordName)
+	}
+}
+
+// ChangeEvent reports every address that changed during a run along with the result for each DNS record updated.
 type ChangeEvent struct {
-	Message string
+	Metadata `json:"-"`
+	Changes  []AddressChange `json:"changes"`
+	Records  []RecordResult  `json:"records"`
 }
 
-func NewChangeEvent(message string) *ChangeEvent {
+func NewChangeEvent(changes []AddressChange, records []RecordResult) *ChangeEvent {
 	return &ChangeEvent{
-		Message: message,
+		Metadata: newMetadata(),
+		Changes:  changes,
+		Records:  records,
+	}
+}
+
+func (e ChangeEvent) Kind() Kind {
+	return KindAddressChanged
+}
+
+// Severity is an error when no record could be updated, a warning when only some could, and info otherwise.
+func (e ChangeEvent) Severity() Severity {
+	failed := len(e.Failed())
+	switch {
+	case failed == 0:
+		return SeverityInfo
+	case failed == len(e.Records):
+		return SeverityError
+	default:
+		return SeverityWarning
+	}
+}
+
+// Title tells which addresses changed, or that records caught up with the current address when none did, noting
+// when some records failed.
+func (e ChangeEvent) Title() string {
+	var title string
+	switch {
+	case e.Recovered():
+		title = "DNS records caught up with the current address"
+	case len(e.Changes) == 1:
+		title = fmt.Sprintf("Public %s address changed", e.Changes[0].Family)
+	default:
+		title = "Public addresses changed"
+	}
+	if len(e.Failed()) > 0 {
+		title += ", some records failed"
 	}
+	return title
+}
+
+// Recovered reports whether the event only carries records that failed on an earlier run and have now been updated,
+// as no address changed.
+func (e ChangeEvent) Recovered() bool {
+	return len(e.Changes) == 0
+}
+
+// Failed returns the records that could not be updated.
+func (e ChangeEvent) Failed() []RecordResult {
+	var failed []RecordResult
+	for _, record := range e.Records {
+		if record.Error != nil {
+			failed = append(failed, record)
+		}
+	}
+	return failed
 }
 
 func (e ChangeEvent) AsMessage() string {
-	return e.Message
+	var lines []string
+	for _, change := range e.Changes {
+		lines = append(lines, change.AsMessage())
+	}
+
+	for _, record := range e.Records {
+		lines = append(lines, record.AsMessage())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// RecordCreatedEvent reports a DNS record that did not exist and was created with IP.
+type RecordCreatedEvent struct {
+	Metadata   `json:"-"`
+	RecordName string      `json:"recordName"`
+	Family     inet.Family `json:"family"`
+	IP         net.IP      `json:"ip"`
+}
+
+func NewRecordCreatedEvent(recordName string, family inet.Family, ip net.IP) *RecordCreatedEvent {
+	return &RecordCreatedEvent{
+		Metadata:   newMetadata(),
+		RecordName: recordName,
+		Family:     family,
+		IP:         ip,
+	}
+}
+
+func (e RecordCreatedEvent) Kind() Kind {
+	return KindRecordCreated
+}
+
+func (e RecordCreatedEvent) Severity() Severity {
+	return SeverityInfo
+}
+
+func (e RecordCreatedEvent) Title() string {
+	return "DNS record created"
+}
+
+func (e RecordCreatedEvent) AsMessage() string {
+	return fmt.Sprintf("DNS %s Record %s did not exist and was created with address %s.", e.Family.RecordType(), e.RecordName, e.IP)
+}
+
+// DriftCorrectedEvent reports a DNS record found holding RecordIP instead of the current public address, CurrentIP,
+// which has since been corrected.
+type DriftCorrectedEvent struct {
+	Metadata   `json:"-"`
+	RecordName string      `json:"recordName"`
+	Family     inet.Family `json:"family"`
+	RecordIP   net.IP      `json:"recordIp"`
+	CurrentIP  net.IP      `json:"currentIp"`
+}
+
+func NewDriftCorrectedEvent(recordName string, family inet.Family, recordIP, currentIP net.IP) *DriftCorrectedEvent {
+	return &DriftCorrectedEvent{
+		Metadata:   newMetadata(),
+		RecordName: recordName,
+		Family:     family,
+		RecordIP:   recordIP,
+		CurrentIP:  currentIP,
+	}
+}
+
+func (e DriftCorrectedEvent) Kind() Kind {
+	return KindDriftCorrected
+}
+
+// Severity is a warning, as something outside of this tool changed the record.
+func (e DriftCorrectedEvent) Severity() Severity {
+	return SeverityWarning
+}
+
+func (e DriftCorrectedEvent) Title() string {
+	return "DNS record drift corrected"
+}
+
+func (e DriftCorrectedEvent) AsMessage() string {
+	return fmt.Sprintf("DNS %s Record %s was set to %s instead of %s and has been corrected.", e.Family.RecordType(), e.RecordName, e.RecordIP, e.CurrentIP)
+}
+
+// SourceAnswer is the address a single IP source returned.
+type SourceAnswer struct {
+	Source string `json:"source"`
+	IP     net.IP `json:"ip"`
+}
+
+// SourcesDisagreeEvent reports IP sources returning different addresses for Family. Chosen is the address that
+// reached quorum, or nil when none did.
+type SourcesDisagreeEvent struct {
+	Metadata `json:"-"`
+	Family   inet.Family    `json:"family"`
+	Answers  []SourceAnswer `json:"answers"`
+	Chosen   net.IP         `json:"chosen,omitempty"`
+}
+
+func NewSourcesDisagreeEvent(family inet.Family, answers []SourceAnswer, chosen net.IP) *SourcesDisagreeEvent {
+	return &SourcesDisagreeEvent{
+		Metadata: newMetadata(),
+		Family:   family,
+		Answers:  answers,
+		Chosen:   chosen,
+	}
+}
+
+func (e SourcesDisagreeEvent) Kind() Kind {
+	return KindSourcesDisagree
+}
+
+func (e SourcesDisagreeEvent) Severity() Severity {
+	return SeverityWarning
+}
+
+func (e SourcesDisagreeEvent) Title() string {
+	return fmt.Sprintf("IP sources disagree on the %s address", e.Family)
+}
+
+func (e SourcesDisagreeEvent) AsMessage() string {
+	var answers []string
+	for _, answer := range e.Answers {
+		answers = append(answers, fmt.Sprintf("%s returned %s", answer.Source, answer.IP))
+	}
+
+	if e.Chosen == nil {
+		return fmt.Sprintf("IP sources disagree on the %s address and none reached quorum: %s.", e.Family, strings.Join(answers, ", "))
+	}
+	return fmt.Sprintf("IP sources disagree on the %s address, using %s: %s.", e.Family, e.Chosen, strings.Join(answers, ", "))
+}
+
+// GatewayBehindNATEvent reports a router whose WAN address, WANIP, is itself behind another NAT, so the public
+// address is not reachable and DNS records pointing at it will not reach this network. CarrierGrade is set when
+// WANIP is in the shared address space of RFC 6598 used by carrier-grade NAT.
+type GatewayBehindNATEvent struct {
+	Metadata     `json:"-"`
+	WANIP        net.IP `json:"wanIp"`
+	CarrierGrade bool   `json:"carrierGrade"`
+}
+
+func NewGatewayBehindNATEvent(wanIP net.IP, carrierGrade bool) *GatewayBehindNATEvent {
+	return &GatewayBehindNATEvent{
+		Metadata:     newMetadata(),
+		WANIP:        wanIP,
+		CarrierGrade: carrierGrade,
+	}
+}
+
+func (e GatewayBehindNATEvent) Kind() Kind {
+	return KindGatewayBehindNAT
+}
+
+func (e GatewayBehindNATEvent) Severity() Severity {
+	return SeverityWarning
+}
+
+func (e GatewayBehindNATEvent) Title() string {
+	return "Router is behind another NAT"
+}
+
+func (e GatewayBehindNATEvent) AsMessage() string {
+	if e.CarrierGrade {
+		return fmt.Sprintf("Router WAN address %s is in the carrier-grade NAT range 100.64.0.0/10. The public address is shared with other customers, so DNS updates will not make this network reachable.", e.WANIP)
+	}
+	return fmt.Sprintf("Router WAN address %s is a private address, so the router is behind another NAT and its address cannot be published.", e.WANIP)
 }
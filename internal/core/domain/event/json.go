@@ -0,0 +1,87 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Envelope is the JSON encoding of every event. Data holds the fields of the event itself, which depend on Kind.
+type Envelope struct {
+	Kind      Kind      `json:"kind"`
+	Severity  Severity  `json:"severity"`
+	Timestamp time.Time `json:"timestamp"`
+	Host      string    `json:"host,omitempty"`
+	RunID     string    `json:"runId,omitempty"`
+	Message   string    `json:"message"`
+	Data      Event     `json:"data"`
+}
+
+// Marshal encodes e as an Envelope, for notifiers and sinks that take JSON.
+func Marshal(e Event) ([]byte, error) {
+	meta := e.Meta()
+	return json.Marshal(Envelope{
+		Kind:      e.Kind(),
+		Severity:  e.Severity(),
+		Timestamp: meta.Timestamp,
+		Host:      meta.Host,
+		RunID:     meta.RunID,
+		Message:   e.AsMessage(),
+		Data:      e,
+	})
+}
+
+// ErrorDetail is the JSON encoding of an error. Chain lists the message of the error and every error it wraps,
+// outermost first, so the root cause can be read without parsing Message. It is empty when nothing is wrapped.
+type ErrorDetail struct {
+	Message string   `json:"message"`
+	Chain   []string `json:"chain,omitempty"`
+}
+
+// NewErrorDetail returns the detail of err, or nil when err is nil.
+func NewErrorDetail(err error) *ErrorDetail {
+	if err == nil {
+		return nil
+	}
+
+	detail := &ErrorDetail{Message: err.Error()}
+	if chain := ErrorChain(err); len(chain) > 1 {
+		detail.Chain = chain
+	}
+	return detail
+}
+
+// ErrorChain returns the messages of err and every error it wraps, outermost first. Joined errors are walked in
+// order.
+func ErrorChain(err error) []string {
+	var chain []string
+	for err != nil {
+		chain = append(chain, err.Error())
+		switch wrapped := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, inner := range wrapped.Unwrap() {
+				chain = append(chain, ErrorChain(inner)...)
+			}
+			return chain
+		default:
+			err = errors.Unwrap(err)
+		}
+	}
+	return chain
+}
+
+func (e FailedUpdateEvent) MarshalJSON() ([]byte, error) {
+	type fields FailedUpdateEvent
+	return json.Marshal(struct {
+		fields
+		Error *ErrorDetail `json:"error,omitempty"`
+	}{fields(e), NewErrorDetail(e.Error)})
+}
+
+func (r RecordResult) MarshalJSON() ([]byte, error) {
+	type fields RecordResult
+	return json.Marshal(struct {
+		fields
+		Error *ErrorDetail `json:"error,omitempty"`
+	}{fields(r), NewErrorDetail(r.Error)})
+}
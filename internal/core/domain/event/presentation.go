@@ -0,0 +1,148 @@
+package event
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+)
+
+// Presentation lays out an event for notifiers that show more than plain text, leaving only the markup to each of
+// them. Description is an error message to show verbatim when Preformatted is set.
+type Presentation struct {
+	Title        string
+	Description  string
+	Preformatted bool
+	Fields       []Field
+}
+
+// Field is a labelled detail of an event, with a line per value. Inline is set when the value is short enough to be
+// shown beside other fields.
+type Field struct {
+	Name   string
+	Lines  []Line
+	Inline bool
+}
+
+// Line is a Literal, such as an address or a record name that notifiers show in a monospace font, followed by Text.
+type Line struct {
+	Literal string
+	Text    string
+}
+
+func (l Line) String() string {
+	return l.Literal + l.Text
+}
+
+// Present lays out e with fields for the details of known event types, falling back to its plain text message as the
+// description.
+func Present(e Event) Presentation {
+	p := Presentation{Title: e.Title()}
+
+	switch e := e.(type) {
+	case *ChangeEvent:
+		p.Fields = changeFields(e)
+	case *FailedUpdateEvent:
+		p.Description = fmt.Sprint(e.Error)
+		p.Preformatted = true
+		if e.RecordName != "" {
+			p.Fields = append(p.Fields, recordField(e.RecordName, e.Family))
+		}
+	case *RecordCreatedEvent:
+		p.Fields = []Field{
+			recordField(e.RecordName, e.Family),
+			addressField("Address", e.IP),
+		}
+	case *DriftCorrectedEvent:
+		p.Fields = []Field{
+			recordField(e.RecordName, e.Family),
+			addressField("Found", e.RecordIP),
+			addressField("Corrected to", e.CurrentIP),
+		}
+	case *SourcesDisagreeEvent:
+		for _, answer := range e.Answers {
+			p.Fields = append(p.Fields, addressField(answer.Source, answer.IP))
+		}
+		chosen := Field{Name: "Using", Lines: []Line{{Text: "None reached quorum"}}}
+		if e.Chosen != nil {
+			chosen = Field{Name: "Using", Lines: []Line{addressLine(e.Chosen)}}
+		}
+		p.Fields = append(p.Fields, chosen)
+	case *GatewayBehindNATEvent:
+		p.Description = e.AsMessage()
+		p.Fields = []Field{addressField("WAN address", e.WANIP)}
+	default:
+		p.Description = e.AsMessage()
+	}
+
+	return p
+}
+
+// changeFields lists each address change of a change event followed by the records updated and failed.
+func changeFields(e *ChangeEvent) []Field {
+	var fields []Field
+	for _, change := range e.Changes {
+		fields = append(fields,
+			addressField(fmt.Sprintf("Old %s", change.Family), change.PreviousIP),
+			addressField(fmt.Sprintf("New %s", change.Family), change.CurrentIP),
+		)
+		if held := change.HeldFor(e.Timestamp); held > 0 {
+			fields = append(fields, textField("Previous address held for", FormatDuration(held)))
+		}
+		if isp := change.NetworkChange(); isp != "" {
+			fields = append(fields, textField("ISP", isp))
+		}
+		if change.CurrentInfo != nil && change.CurrentInfo.Location() != "" {
+			fields = append(fields, textField("Location", change.CurrentInfo.Location()))
+		}
+		if change.NATMapping != "" {
+			fields = append(fields, textField("NAT mapping", string(change.NATMapping)))
+		}
+	}
+
+	var updated, failed []Line
+	for _, record := range e.Records {
+		line := recordLine(record.RecordName, record.Family)
+		switch {
+		case record.Error != nil:
+			line.Text += fmt.Sprintf(": %s", record.Error)
+			failed = append(failed, line)
+		case record.Created:
+			line.Text += " (created)"
+			updated = append(updated, line)
+		default:
+			updated = append(updated, line)
+		}
+	}
+	if len(updated) > 0 {
+		fields = append(fields, Field{Name: "Records updated", Lines: updated})
+	}
+	if len(failed) > 0 {
+		fields = append(fields, Field{Name: "Records failed", Lines: failed})
+	}
+
+	return fields
+}
+
+func recordField(recordName string, family inet.Family) Field {
+	return Field{Name: "Record", Lines: []Line{recordLine(recordName, family)}, Inline: true}
+}
+
+func recordLine(recordName string, family inet.Family) Line {
+	return Line{Literal: recordName, Text: " " + family.RecordType()}
+}
+
+func addressField(name string, ip net.IP) Field {
+	return Field{Name: name, Lines: []Line{addressLine(ip)}, Inline: true}
+}
+
+func addressLine(ip net.IP) Line {
+	if ip == nil {
+		return Line{Text: "None"}
+	}
+	return Line{Literal: ip.String()}
+}
+
+func textField(name, text string) Field {
+	return Field{Name: name, Lines: []Line{{Text: text}}, Inline: true}
+}
@@ -0,0 +1,74 @@
+package inet
+
+import "net"
+
+// bogon is a range of addresses that are never reachable on the public internet.
+type bogon struct {
+	network *net.IPNet
+	kind    string
+}
+
+var (
+	carrierGradeNAT = mustParseCIDR("100.64.0.0/10")
+
+	bogons = []bogon{
+		{mustParseCIDR("0.0.0.0/8"), "unspecified"},
+		{mustParseCIDR("10.0.0.0/8"), "private"},
+		{carrierGradeNAT, "carrier-grade NAT"},
+		{mustParseCIDR("127.0.0.0/8"), "loopback"},
+		{mustParseCIDR("169.254.0.0/16"), "link-local"},
+		{mustParseCIDR("172.16.0.0/12"), "private"},
+		{mustParseCIDR("192.0.0.0/24"), "reserved"},
+		{mustParseCIDR("192.0.2.0/24"), "documentation"},
+		{mustParseCIDR("192.168.0.0/16"), "private"},
+		{mustParseCIDR("198.18.0.0/15"), "benchmarking"},
+		{mustParseCIDR("198.51.100.0/24"), "documentation"},
+		{mustParseCIDR("203.0.113.0/24"), "documentation"},
+		{mustParseCIDR("224.0.0.0/4"), "multicast"},
+		{mustParseCIDR("240.0.0.0/4"), "reserved"},
+		{mustParseCIDR("::/128"), "unspecified"},
+		{mustParseCIDR("::1/128"), "loopback"},
+		{mustParseCIDR("100::/64"), "discard"},
+		{mustParseCIDR("2001:db8::/32"), "documentation"},
+		{mustParseCIDR("3fff::/20"), "documentation"},
+		{mustParseCIDR("fc00::/7"), "unique local"},
+		{mustParseCIDR("fe80::/10"), "link-local"},
+		{mustParseCIDR("ff00::/8"), "multicast"},
+	}
+
+	// globalUnicast is the only IPv6 range allocated for public addresses.
+	globalUnicast = mustParseCIDR("2000::/3")
+)
+
+// BogonKind returns what kind of address ip is, such as "private" or "documentation", when it can never be reached
+// from the public internet, and an empty string when it is a public address.
+func BogonKind(ip net.IP) string {
+	if ip.To4() == nil && len(ip) != net.IPv6len {
+		return "invalid"
+	}
+
+	for _, bogon := range bogons {
+		if bogon.network.Contains(ip) {
+			return bogon.kind
+		}
+	}
+
+	if ip.To4() == nil && !globalUnicast.Contains(ip) {
+		return "reserved"
+	}
+
+	return ""
+}
+
+// IsCarrierGradeNAT reports whether ip is in the shared address space of RFC 6598, used by carrier-grade NAT.
+func IsCarrierGradeNAT(ip net.IP) bool {
+	return carrierGradeNAT.Contains(ip)
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
@@ -0,0 +1,76 @@
+package inet
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Family is an IP address family.
+type Family int
+
+const (
+	IPv4 Family = iota + 1
+	IPv6
+)
+
+var ErrInvalidAddress = errors.New("invalid IP address")
+
+// FamilyOf returns the family of ip, or ErrInvalidAddress when ip is nil or malformed.
+func FamilyOf(ip net.IP) (Family, error) {
+	if ip.To4() != nil {
+		return IPv4, nil
+	}
+	if len(ip) == net.IPv6len {
+		return IPv6, nil
+	}
+	return 0, ErrInvalidAddress
+}
+
+// Matches reports whether ip belongs to the family.
+func (f Family) Matches(ip net.IP) bool {
+	family, err := FamilyOf(ip)
+	return err == nil && family == f
+}
+
+// RecordType returns the DNS record type that holds addresses of the family.
+func (f Family) RecordType() string {
+	if f == IPv6 {
+		return "AAAA"
+	}
+	return "A"
+}
+
+func (f Family) String() string {
+	switch f {
+	case IPv4:
+		return "IPv4"
+	case IPv6:
+		return "IPv6"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the family as "ipv4" or "ipv6", as used in the configuration.
+func (f Family) MarshalText() ([]byte, error) {
+	switch f {
+	case IPv4, IPv6:
+		return []byte(strings.ToLower(f.String())), nil
+	default:
+		return nil, fmt.Errorf("unknown address family %d", f)
+	}
+}
+
+func (f *Family) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "ipv4":
+		*f = IPv4
+	case "ipv6":
+		*f = IPv6
+	default:
+		return fmt.Errorf("unknown address family %q", text)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package inet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IPInfo describes the network a public address is routed to and roughly where it is.
+type IPInfo struct {
+	// ASN is the number of the autonomous system announcing the address, or zero when unknown.
+	ASN         uint32 `json:"asn,omitempty"`
+	ASName      string `json:"asName,omitempty"`
+	ISP         string `json:"isp,omitempty"`
+	Org         string `json:"org,omitempty"`
+	Country     string `json:"country,omitempty"`
+	CountryCode string `json:"countryCode,omitempty"`
+	Region      string `json:"region,omitempty"`
+	City        string `json:"city,omitempty"`
+	// Mobile is set for addresses of cellular networks, such as after failing over to LTE.
+	Mobile bool `json:"mobile"`
+	// Proxy is set for addresses of proxies, VPNs and Tor exit nodes.
+	Proxy bool `json:"proxy"`
+	// Hosting is set for addresses of hosting providers and data centers.
+	Hosting bool `json:"hosting"`
+}
+
+// Network names the ISP and autonomous system, such as "Example ISP (AS64500)".
+func (i IPInfo) Network() string {
+	name := i.ISP
+	if name == "" {
+		name = i.ASName
+	}
+
+	switch {
+	case name != "" && i.ASN != 0:
+		return fmt.Sprintf("%s (AS%d)", name, i.ASN)
+	case i.ASN != 0:
+		return fmt.Sprintf("AS%d", i.ASN)
+	default:
+		return name
+	}
+}
+
+// Location joins the city, region and country that are known, such as "Berlin, Berlin, Germany".
+func (i IPInfo) Location() string {
+	var parts []string
+	for _, part := range []string{i.City, i.Region, i.Country} {
+		if part != "" && (len(parts) == 0 || parts[len(parts)-1] != part) {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Kinds lists the kinds of network the address belongs to that are worth pointing out: mobile, proxy or hosting.
+func (i IPInfo) Kinds() []string {
+	var kinds []string
+	if i.Mobile {
+		kinds = append(kinds, "mobile")
+	}
+	if i.Proxy {
+		kinds = append(kinds, "proxy")
+	}
+	if i.Hosting {
+		kinds = append(kinds, "hosting")
+	}
+	return kinds
+}
@@ -0,0 +1,18 @@
+package inet
+
+// NATMapping is how a NAT maps an internal address and port to a public one, as defined by RFC 4787. The zero
+// value means the mapping is unknown.
+type NATMapping string
+
+const (
+	// NATMappingNone means the address is not translated.
+	NATMappingNone NATMapping = "none"
+	// NATMappingEndpointIndependent reuses the same public address and port for every destination.
+	NATMappingEndpointIndependent NATMapping = "endpoint-independent"
+	// NATMappingAddressDependent uses a new public port for each destination address.
+	NATMappingAddressDependent NATMapping = "address-dependent"
+	// NATMappingAddressAndPortDependent uses a new public port for each destination address and port.
+	NATMappingAddressAndPortDependent NATMapping = "address-and-port-dependent"
+	// NATMappingEndpointDependent is either dependent mapping, when the two cannot be told apart.
+	NATMappingEndpointDependent NATMapping = "endpoint-dependent"
+)
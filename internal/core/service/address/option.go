@@ -0,0 +1,56 @@
+package address
+
+import (
+	"net"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
+)
+
+type Option func(*Service)
+
+// WithReconciliation compares each record's published address with the current public address, correcting any
+// drift, at most once per interval. An interval of zero reconciles on every run.
+func WithReconciliation(interval time.Duration) Option {
+	return func(s *Service) {
+		s.reconcile = true
+		s.reconcileInterval = interval
+	}
+}
+
+// WithAllowedPrefixes only publishes addresses within one of prefixes. Addresses in these prefixes are published even
+// when they are not publicly routable, such as a private range used on an internal network.
+func WithAllowedPrefixes(prefixes []*net.IPNet) Option {
+	return func(s *Service) {
+		s.allowedPrefixes = prefixes
+	}
+}
+
+// WithAllowedASNs only publishes addresses announced by one of asns, as looked up with provider, such as the
+// networks of the ISPs serving this site.
+func WithAllowedASNs(asns []uint32, provider gateway.IPInfoProvider) Option {
+	return func(s *Service) {
+		if len(asns) > 0 && provider != nil {
+			s.allowedASNs = asns
+			s.ipInfoProvider = provider
+		}
+	}
+}
+
+// WithEnrichment adds information about the network of the previous and current address, such as the ISP and
+// whether it is a mobile network, to change events, as looked up with provider.
+func WithEnrichment(provider gateway.IPInfoProvider) Option {
+	return func(s *Service) {
+		if provider != nil {
+			s.enrich = true
+			s.ipInfoProvider = provider
+		}
+	}
+}
+
+// WithHost names the host running the service in events, such as its hostname.
+func WithHost(host string) Option {
+	return func(s *Service) {
+		s.host = host
+	}
+}
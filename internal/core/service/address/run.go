@@ -0,0 +1,21 @@
+package address
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+type runIDKey struct{}
+
+// contextWithRunID carries an ID identifying a single check of the address, so the events it raises can be grouped.
+func contextWithRunID(ctx context.Context) context.Context {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return context.WithValue(ctx, runIDKey{}, hex.EncodeToString(id))
+}
+
+func runIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
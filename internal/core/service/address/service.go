@@ -2,32 +2,104 @@ package address
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"time"
 
 	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/event"
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
 	"github.com/awlsring/dynamic-ip-watcher/internal/ports/gateway"
 	"github.com/awlsring/dynamic-ip-watcher/internal/ports/service"
 	"github.com/rs/zerolog/log"
 )
 
+// DuplicatePolicy decides what happens when a record name resolves to more than one record of the same type.
+type DuplicatePolicy int
+
+const (
+	// DuplicatePolicyFail refuses to touch any of the records.
+	DuplicatePolicyFail DuplicatePolicy = iota
+	// DuplicatePolicyUpdateAll sets every duplicate to the new address.
+	DuplicatePolicyUpdateAll
+	// DuplicatePolicyDeleteDuplicates deletes all but one record, then updates the one left.
+	DuplicatePolicyDeleteDuplicates
+)
+
+// Record is a DNS record kept up to date by the service and the address families published to it. ID identifies it
+// in storage and defaults to the name of the record.
+type Record struct {
+	ID              gateway.RecordID
+	Updater         gateway.DNSUpdater
+	Families        []inet.Family
+	CreateIfMissing bool
+	DuplicatePolicy DuplicatePolicy
+}
+
+func (r Record) id() gateway.RecordID {
+	if r.ID == (gateway.RecordID{}) {
+		return gateway.RecordID{Name: r.Updater.RecordName()}
+	}
+	return r.ID
+}
+
+func (r Record) publishes(family inet.Family) bool {
+	for _, f := range r.Families {
+		if f == family {
+			return true
+		}
+	}
+	return false
+}
+
+// recoverable reports whether publishing to the record can still succeed after err was returned when reading it.
+func (r Record) recoverable(err error) bool {
+	switch {
+	case errors.Is(err, gateway.ErrRecordNotFound):
+		return r.CreateIfMissing
+	case errors.Is(err, gateway.ErrMultipleRecordsFound):
+		return r.DuplicatePolicy != DuplicatePolicyFail
+	default:
+		return false
+	}
+}
+
 type Service struct {
-	dnsUpdater  gateway.DNSUpdater
-	ipRetriever gateway.IPRetriever
-	notifiers   []gateway.Notifier
-	storage     gateway.Storage
+	records           []Record
+	ipRetriever       gateway.IPRetriever
+	notifiers         []gateway.Notifier
+	storage           gateway.Storage
+	reconcile         bool
+	reconcileInterval time.Duration
+	allowedPrefixes   []*net.IPNet
+	allowedASNs       []uint32
+	enrich            bool
+	ipInfoProvider    gateway.IPInfoProvider
+	host              string
 }
 
-func NewService(dnsUpdater gateway.DNSUpdater, ipRetriever gateway.IPRetriever, notifiers []gateway.Notifier, storage gateway.Storage) service.Address {
-	return &Service{
-		dnsUpdater:  dnsUpdater,
+func NewService(records []Record, ipRetriever gateway.IPRetriever, notifiers []gateway.Notifier, storage gateway.Storage, opts ...Option) service.Address {
+	service := &Service{
+		records:     records,
 		ipRetriever: ipRetriever,
 		notifiers:   notifiers,
 		storage:     storage,
 	}
+
+	for _, opt := range opts {
+		opt(service)
+	}
+
+	return service
 }
 
+// sendEventToNotifiers stamps event with the host and the ID of the current run and sends it to every notifier.
 func (s *Service) sendEventToNotifiers(ctx context.Context, event event.Event) {
-	log.Info().Msg("Sending event to notifiers")
+	meta := event.Meta()
+	meta.Host = s.host
+	meta.RunID = runIDFromContext(ctx)
+
+	log.Info().Str("kind", string(event.Kind())).Msg("Sending event to notifiers")
 	for _, notifier := range s.notifiers {
 		err := notifier.SendEventMessage(ctx, event)
 		if err != nil {
@@ -36,56 +108,356 @@ func (s *Service) sendEventToNotifiers(ctx context.Context, event event.Event) {
 	}
 }
 
+// families returns every address family published by at least one record. IPv4 is watched when no records are configured.
+func (s *Service) families() []inet.Family {
+	if len(s.records) == 0 {
+		return []inet.Family{inet.IPv4}
+	}
+
+	var families []inet.Family
+	for _, family := range []inet.Family{inet.IPv4, inet.IPv6} {
+		for _, record := range s.records {
+			if record.publishes(family) {
+				families = append(families, family)
+				break
+			}
+		}
+	}
+	return families
+}
+
+// DetectAndHandleAddressChange checks every address family in use and publishes the current address to each
+// record that has not yet successfully published it. Records already holding the current address are reconciled
+// against the DNS provider instead, if enabled. A failure for one family or record does not prevent the others from
+// being handled; the outcome for every record published to is reported in a single change event and all failures
+// are returned together.
 func (s *Service) DetectAndHandleAddressChange(ctx context.Context) error {
-	var eventMessage event.Event
-	defer func() {
-		s.sendEventToNotifiers(ctx, eventMessage)
-	}()
+	ctx = contextWithRunID(ctx)
+
+	var errs []error
+	var changes []event.AddressChange
+	var results []event.RecordResult
+
+	for _, family := range s.families() {
+		change, changed, err := s.detectAddressChange(ctx, family)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", family, err))
+			continue
+		}
+		if changed {
+			changes = append(changes, change)
+		}
+
+		familyResults, familyErrs := s.updateRecords(ctx, family, change.CurrentIP)
+		results = append(results, familyResults...)
+		errs = append(errs, familyErrs...)
+	}
+
+	// a publish that keeps failing while the address is unchanged has already been reported, so only notify
+	// about retries once one succeeds
+	if len(changes) > 0 || anySucceeded(results) {
+		s.sendEventToNotifiers(ctx, event.NewChangeEvent(changes, results))
+	}
 
-	log.Info().Msg("Detecting IP address change")
-	previousIP, err := s.storage.GetLastKnownIPAddress(ctx)
+	for _, result := range results {
+		if result.Error != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", result.RecordName, result.Family.RecordType(), result.Error))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func anySucceeded(results []event.RecordResult) bool {
+	for _, result := range results {
+		if result.Error == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// detectAddressChange returns the previously observed and current address for family and whether they differ. A
+// changed address is saved as observed before returning; whether it was published is tracked per record.
+func (s *Service) detectAddressChange(ctx context.Context, family inet.Family) (event.AddressChange, bool, error) {
+	logger := log.With().Stringer("family", family).Logger()
+
+	logger.Info().Msg("Detecting IP address change")
+	previousIP, err := s.storage.GetLastKnownIPAddress(ctx, family)
 	if err != nil {
-		eventMessage = event.NewFailedUpdateEvent("Failed to determine the last known IP address", err)
-		log.Error().Err(err).Msg("Failed to get last known IP address")
-		return err
+		s.sendEventToNotifiers(ctx, event.NewFailedUpdateEvent(family, event.OperationReadLastKnownAddress, err))
+		logger.Error().Err(err).Msg("Failed to get last known IP address")
+		return event.AddressChange{}, false, err
 	}
-	log.Info().Str("previous_ip", previousIP.String()).Msg("Previous IP address")
+	logger.Info().Str("previous_ip", previousIP.String()).Msg("Previous IP address")
 
-	log.Info().Msg("Retrieving current IP address")
-	currentIP, err := s.ipRetriever.GetPublicIPv4(ctx)
+	logger.Info().Msg("Retrieving current IP address")
+	currentIP, natMapping, err := s.getPublicIP(ctx, family)
 	if err != nil {
-		eventMessage = event.NewFailedUpdateEvent("Failed to determine current IP address", err)
-		log.Error().Err(err).Msg("Failed to get current IP address")
-		return err
+		s.sendEventToNotifiers(ctx, event.NewFailedUpdateEvent(family, event.OperationRetrieveAddress, err))
+		logger.Error().Err(err).Msg("Failed to get current IP address")
+		return event.AddressChange{}, false, err
+	}
+	logger.Info().Str("current_ip", currentIP.String()).Msg("Current IP address")
+
+	currentInfo, err := s.validateIP(ctx, family, currentIP, previousIP)
+	if err != nil {
+		s.sendEventToNotifiers(ctx, event.NewFailedUpdateEvent(family, event.OperationValidateAddress, err))
+		logger.Error().Err(err).Msg("Current IP address failed validation")
+		return event.AddressChange{}, false, err
+	}
+
+	change := event.AddressChange{
+		Family:     family,
+		PreviousIP: previousIP,
+		CurrentIP:  currentIP,
+		NATMapping: natMapping,
 	}
-	log.Info().Str("current_ip", currentIP.String()).Msg("Current IP address")
 
 	if previousIP.Equal(currentIP) {
-		log.Info().Msg("IP address has not changed")
+		logger.Info().Msg("IP address has not changed")
+		return change, false, nil
+	}
+	logger.Info().Msg("IP address has changed")
+
+	if previousIP != nil {
+		observedAt, err := s.storage.GetIPAddressObservedAt(ctx, family)
+		if err != nil {
+			logger.Warn().Err(err).Msg("Failed to get when the previous IP address was observed")
+		} else if !observedAt.IsZero() {
+			change.PreviousSince = &observedAt
+		}
+	}
+
+	if s.enrich {
+		change.PreviousInfo = s.lookupIPInfo(ctx, previousIP)
+		if change.CurrentInfo = currentInfo; currentInfo == nil {
+			change.CurrentInfo = s.lookupIPInfo(ctx, currentIP)
+		}
+	}
+
+	logger.Info().Msg("Saving current IP address")
+	err = s.storage.SaveIPAddress(ctx, family, currentIP)
+	if err != nil {
+		s.sendEventToNotifiers(ctx, event.NewFailedUpdateEvent(family, event.OperationStoreAddress, err))
+		logger.Error().Err(err).Msg("Failed to save current IP address")
+		return event.AddressChange{}, false, err
+	}
+
+	return change, true, nil
+}
+
+// updateRecords publishes currentIP to every record of family whose last successfully published address differs,
+// committing the published address only once the DNS provider accepts it so a failed publish is retried on the next
+// run. Records already up to date are reconciled instead.
+func (s *Service) updateRecords(ctx context.Context, family inet.Family, currentIP net.IP) ([]event.RecordResult, []error) {
+	var results []event.RecordResult
+	var errs []error
+	for _, record := range s.records {
+		if !record.publishes(family) {
+			continue
+		}
+
+		recordName := record.Updater.RecordName()
+		logger := log.With().Str("record", recordName).Str("type", family.RecordType()).Logger()
+
+		backoffUntil, err := s.storage.GetBackoffUntil(ctx, record.id(), family)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to get back-off time")
+			errs = append(errs, fmt.Errorf("%s %s: %w", recordName, family.RecordType(), err))
+			continue
+		}
+		if time.Now().Before(backoffUntil) {
+			logger.Warn().Time("backoff_until", backoffUntil).Msg("DNS provider asked for updates to stop, skipping record")
+			continue
+		}
+
+		publishedIP, err := s.storage.GetPublishedIPAddress(ctx, record.id(), family)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to get published IP address")
+			errs = append(errs, fmt.Errorf("%s %s: %w", recordName, family.RecordType(), err))
+			continue
+		}
+
+		if publishedIP.Equal(currentIP) {
+			err = s.reconcileRecord(ctx, record, family, currentIP)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", recordName, family.RecordType(), err))
+			}
+			continue
+		}
+
+		logger.Info().Str("published_ip", publishedIP.String()).Msg("Updating DNS record with new IP address")
+		created, err := s.publishToRecord(ctx, record, family, currentIP, publishedIP)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to update DNS record, will retry on next run")
+			s.backOffIfRequested(ctx, record, err)
+		} else {
+			err = s.storage.SavePublishedIPAddress(ctx, record.id(), family, currentIP)
+			if err != nil {
+				logger.Error().Err(err).Msg("Failed to save published IP address")
+			}
+		}
+		if created {
+			logger.Info().Msg("Created missing DNS record")
+			s.sendEventToNotifiers(ctx, event.NewRecordCreatedEvent(recordName, family, currentIP))
+		}
+
+		results = append(results, event.RecordResult{
+			RecordName: recordName,
+			Family:     family,
+			Created:    created,
+			Error:      err,
+		})
+	}
+	return results, errs
+}
+
+// reconcileRecord compares the address held by the record against currentIP and corrects it if it has drifted,
+// such as after a manual edit at the DNS provider.
+func (s *Service) reconcileRecord(ctx context.Context, record Record, family inet.Family, currentIP net.IP) error {
+	if !s.reconcile {
+		return nil
+	}
+
+	recordName := record.Updater.RecordName()
+	logger := log.With().Str("record", recordName).Str("type", family.RecordType()).Logger()
+
+	lastReconciledAt, err := s.storage.GetLastReconciledAt(ctx, record.id(), family)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to get last reconciliation time")
+		return err
+	}
+	if time.Since(lastReconciledAt) < s.reconcileInterval {
+		logger.Debug().Time("last_reconciled_at", lastReconciledAt).Msg("DNS record reconciled recently, skipping")
 		return nil
 	}
-	log.Info().Msg("IP address has changed")
-	eventMessage = event.NewChangeEvent("IP address changed from " + previousIP.String() + " to " + currentIP.String())
 
-	log.Info().Msg("Saving current IP address")
-	err = s.storage.SaveIPAddress(ctx, currentIP)
+	logger.Info().Msg("Reconciling DNS record with current IP address")
+	recordIP, err := record.Updater.GetRecordIpAddress(ctx, family)
+	if errors.Is(err, gateway.ErrRecordReadNotSupported) {
+		logger.Debug().Msg("DNS provider cannot read the record, skipping reconciliation")
+		return nil
+	}
+	if err != nil && !record.recoverable(err) {
+		s.sendEventToNotifiers(ctx, event.NewFailedRecordUpdateEvent(recordName, family, event.OperationReadRecord, err))
+		logger.Error().Err(err).Msg("Failed to get DNS record IP address")
+		return err
+	}
+
+	if err == nil && recordIP.Equal(currentIP) {
+		logger.Info().Msg("DNS record matches current IP address")
+		return s.storage.SaveLastReconciledAt(ctx, record.id(), family, time.Now())
+	}
+
+	logger.Warn().Str("record_ip", recordIP.String()).Str("current_ip", currentIP.String()).Msg("DNS record has drifted from current IP address, correcting")
+	created, err := s.publishToRecord(ctx, record, family, currentIP, recordIP)
+	if err != nil {
+		s.sendEventToNotifiers(ctx, event.NewFailedRecordUpdateEvent(recordName, family, event.OperationCorrectRecord, err))
+		logger.Error().Err(err).Msg("Failed to correct DNS record")
+		s.backOffIfRequested(ctx, record, err)
+		return err
+	}
+
+	if created {
+		s.sendEventToNotifiers(ctx, event.NewRecordCreatedEvent(recordName, family, currentIP))
+	} else {
+		s.sendEventToNotifiers(ctx, event.NewDriftCorrectedEvent(recordName, family, recordIP, currentIP))
+	}
+
+	err = s.storage.SavePublishedIPAddress(ctx, record.id(), family, currentIP)
 	if err != nil {
-		eventMessage = event.NewFailedUpdateEvent("Failed to store new IP address", err)
-		log.Error().Err(err).Msg("Failed to save current IP address")
 		return err
 	}
 
-	if s.dnsUpdater != nil {
-		log.Info().Msg("Updating DNS A record with new IP address")
-		err = s.dnsUpdater.UpdateRecordIpAddress(ctx, currentIP)
+	return s.storage.SaveLastReconciledAt(ctx, record.id(), family, time.Now())
+}
+
+// backOffIfRequested stops publishing to every family of the record when err is a gateway.BackoffError. The
+// back-off is stored so it holds across runs.
+func (s *Service) backOffIfRequested(ctx context.Context, record Record, err error) {
+	var backoff *gateway.BackoffError
+	if !errors.As(err, &backoff) {
+		return
+	}
+
+	recordName := record.Updater.RecordName()
+	until := time.Now().Add(backoff.RetryAfter)
+	log.Warn().Str("record", recordName).Time("backoff_until", until).Msg("DNS provider asked for updates to stop, backing off")
+	for _, family := range record.Families {
+		err := s.storage.SaveBackoffUntil(ctx, record.id(), family, until)
+		if err != nil {
+			log.Error().Err(err).Str("record", recordName).Msg("Failed to save back-off time")
+		}
+	}
+}
+
+// publishToRecord updates the record from previousIP to ip, creating the record or resolving duplicates when the
+// record's settings allow it. created reports whether a new record was made.
+func (s *Service) publishToRecord(ctx context.Context, record Record, family inet.Family, ip, previousIP net.IP) (created bool, err error) {
+	err = record.Updater.UpdateRecordIpAddress(ctx, ip, previousIP)
+	switch {
+	case errors.Is(err, gateway.ErrRecordNotFound) && record.CreateIfMissing:
+		log.Info().Str("record", record.Updater.RecordName()).Msg("DNS record not found, creating it")
+		err = record.Updater.CreateRecordWithIpAddress(ctx, ip)
+		return err == nil, err
+	case errors.Is(err, gateway.ErrMultipleRecordsFound):
+		return false, s.resolveDuplicateRecords(ctx, record, family, ip, previousIP)
+	default:
+		return false, err
+	}
+}
+
+func (s *Service) resolveDuplicateRecords(ctx context.Context, record Record, family inet.Family, ip, previousIP net.IP) error {
+	if record.DuplicatePolicy == DuplicatePolicyFail {
+		return fmt.Errorf("%w, refusing to update without a duplicate policy", gateway.ErrMultipleRecordsFound)
+	}
+
+	resolver, ok := record.Updater.(gateway.DuplicateRecordResolver)
+	if !ok {
+		return fmt.Errorf("%w and the DNS provider cannot resolve duplicates", gateway.ErrMultipleRecordsFound)
+	}
+
+	switch record.DuplicatePolicy {
+	case DuplicatePolicyUpdateAll:
+		log.Warn().Str("record", record.Updater.RecordName()).Msg("Multiple DNS records found, updating all of them")
+		return resolver.UpdateAllRecordsIpAddress(ctx, ip)
+	case DuplicatePolicyDeleteDuplicates:
+		log.Warn().Str("record", record.Updater.RecordName()).Msg("Multiple DNS records found, deleting duplicates")
+		err := resolver.DeleteDuplicateRecords(ctx, family)
 		if err != nil {
-			eventMessage = event.NewFailedUpdateEvent("Failed to update DNS Record with new IP address", err)
-			log.Error().Err(err).Msg("Failed to update DNS A record")
 			return err
 		}
-		message := fmt.Sprintf("IP address changed from %s to %s. DNS Record %s updated with new address.", previousIP.String(), currentIP.String(), s.dnsUpdater.RecordName())
-		eventMessage = event.NewChangeEvent(message)
+		return record.Updater.UpdateRecordIpAddress(ctx, ip, previousIP)
+	default:
+		return fmt.Errorf("%w, unknown duplicate policy %d", gateway.ErrMultipleRecordsFound, record.DuplicatePolicy)
+	}
+}
+
+// lookupIPInfo returns information about the network of ip for change events, or nil when ip is nil or the lookup
+// fails. A failed lookup only loses detail from the event, so it is logged rather than reported.
+func (s *Service) lookupIPInfo(ctx context.Context, ip net.IP) *inet.IPInfo {
+	if ip == nil {
+		return nil
+	}
+
+	info, err := s.ipInfoProvider.GetIPInfo(ctx, ip)
+	if err != nil {
+		log.Warn().Err(err).Str("ip", ip.String()).Msg("Failed to look up IP address information")
+		return nil
+	}
+
+	return &info
+}
+
+// getPublicIP retrieves the current address of family along with the NAT mapping, if the retriever detected it.
+// Warnings raised by the retriever are sent to the notifiers.
+func (s *Service) getPublicIP(ctx context.Context, family inet.Family) (net.IP, inet.NATMapping, error) {
+	retrieval, err := gateway.RetrievePublicIP(ctx, s.ipRetriever, family)
+	for _, warning := range retrieval.Warnings {
+		log.Warn().Stringer("family", family).Msg(warning.AsMessage())
+		s.sendEventToNotifiers(ctx, warning)
 	}
 
-	return nil
+	return retrieval.IP, retrieval.NATMapping, err
 }
@@ -0,0 +1,53 @@
+package address
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+)
+
+var (
+	ErrAddressRejected = errors.New("address rejected")
+)
+
+// validateIP rejects an address returned by the IP retriever that must not be published: a missing address, one of
+// another family, or one that is not publicly routable, such as a private or documentation address. When allowed
+// prefixes are set the address must be within one of them, which also permits addresses that are not public. When
+// allowed ASNs are set the network announcing the address is looked up, only when it differs from previousIP as an
+// unchanged address was already checked, and the information looked up is returned.
+func (s *Service) validateIP(ctx context.Context, family inet.Family, ip, previousIP net.IP) (*inet.IPInfo, error) {
+	if ip == nil {
+		return nil, fmt.Errorf("%w: no address was returned", ErrAddressRejected)
+	}
+
+	if !family.Matches(ip) {
+		return nil, fmt.Errorf("%w: %s is not an %s address", ErrAddressRejected, ip, family)
+	}
+
+	if len(s.allowedPrefixes) > 0 {
+		if !slices.ContainsFunc(s.allowedPrefixes, func(prefix *net.IPNet) bool { return prefix.Contains(ip) }) {
+			return nil, fmt.Errorf("%w: %s is not within an allowed prefix", ErrAddressRejected, ip)
+		}
+	} else if kind := inet.BogonKind(ip); kind != "" {
+		return nil, fmt.Errorf("%w: %s is a %s address", ErrAddressRejected, ip, kind)
+	}
+
+	if len(s.allowedASNs) == 0 || ip.Equal(previousIP) {
+		return nil, nil
+	}
+
+	info, err := s.ipInfoProvider.GetIPInfo(ctx, ip)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not look up the network announcing %s: %w", ErrAddressRejected, ip, err)
+	}
+
+	if !slices.Contains(s.allowedASNs, info.ASN) {
+		return nil, fmt.Errorf("%w: %s is announced by AS%d %s, which is not an allowed network", ErrAddressRejected, ip, info.ASN, info.ASName)
+	}
+
+	return &info, nil
+}
@@ -0,0 +1,165 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTimeout = 30 * time.Second
+
+	maxOutputBytes = 64 * 1024
+	maxStderrLen   = 512
+	waitDelay      = 2 * time.Second
+)
+
+var (
+	// DefaultAllowedEnv is the environment passed through from this process unless replaced with WithAllowedEnv.
+	DefaultAllowedEnv = []string{"PATH", "HOME", "LANG", "LC_ALL", "TZ"}
+
+	ErrEmptyCommand = errors.New("command is empty")
+)
+
+// ExitError is returned when a command fails or times out. Stderr holds what the command wrote to stderr, so the
+// reason is included in logs and notifications.
+type ExitError struct {
+	Command  string
+	ExitCode int
+	TimedOut bool
+	Stderr   string
+	Err      error
+}
+
+func (e *ExitError) Error() string {
+	var msg string
+	switch {
+	case e.TimedOut:
+		msg = fmt.Sprintf("%s timed out", e.Command)
+	case e.ExitCode >= 0:
+		msg = fmt.Sprintf("%s exited with status %d", e.Command, e.ExitCode)
+	default:
+		msg = fmt.Sprintf("%s failed: %s", e.Command, e.Err)
+	}
+
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// Runner runs commands directly, without a shell, with a timeout and an environment built only from allowed
+// variables of this process, the variables it was configured with and those given to Run.
+type Runner struct {
+	timeout    time.Duration
+	allowedEnv []string
+	env        map[string]string
+}
+
+func New(opts ...Option) *Runner {
+	runner := &Runner{
+		timeout:    DefaultTimeout,
+		allowedEnv: DefaultAllowedEnv,
+	}
+
+	for _, opt := range opts {
+		opt(runner)
+	}
+
+	return runner
+}
+
+// Run runs args[0] with the remaining arguments and returns its stdout. env is added to the environment, taking
+// precedence over the configured variables.
+func (r *Runner) Run(ctx context.Context, args []string, env map[string]string) ([]byte, error) {
+	if len(args) == 0 || args[0] == "" {
+		return nil, ErrEmptyCommand
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{limit: maxOutputBytes}
+	stderr := &limitedBuffer{limit: maxOutputBytes}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = r.environ(env)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// stop waiting on output held open by processes the command left behind
+	cmd.WaitDelay = waitDelay
+
+	err := cmd.Run()
+	if err == nil {
+		return stdout.Bytes(), nil
+	}
+
+	exitErr := &ExitError{
+		Command:  args[0],
+		ExitCode: -1,
+		TimedOut: errors.Is(ctx.Err(), context.DeadlineExceeded),
+		Stderr:   truncate(stderr.String()),
+		Err:      err,
+	}
+
+	var processErr *exec.ExitError
+	if errors.As(err, &processErr) {
+		exitErr.ExitCode = processErr.ExitCode()
+	}
+
+	return nil, exitErr
+}
+
+func (r *Runner) environ(env map[string]string) []string {
+	values := map[string]string{}
+	for _, name := range r.allowedEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			values[name] = value
+		}
+	}
+	for name, value := range r.env {
+		values[name] = value
+	}
+	for name, value := range env {
+		values[name] = value
+	}
+
+	environ := make([]string, 0, len(values))
+	for name, value := range values {
+		environ = append(environ, name+"="+value)
+	}
+	sort.Strings(environ)
+
+	return environ
+}
+
+// limitedBuffer keeps the first limit bytes written and discards the rest, so a noisy command cannot exhaust memory.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); remaining > 0 {
+		b.Buffer.Write(p[:min(len(p), remaining)])
+	}
+	return len(p), nil
+}
+
+func truncate(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) > maxStderrLen {
+		return text[:maxStderrLen] + "..."
+	}
+	return text
+}
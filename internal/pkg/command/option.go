@@ -0,0 +1,31 @@
+package command
+
+import "time"
+
+type Option func(*Runner)
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Runner) {
+		if timeout > 0 {
+			r.timeout = timeout
+		}
+	}
+}
+
+// WithAllowedEnv replaces DefaultAllowedEnv with the names of the variables passed through from this process.
+func WithAllowedEnv(names []string) Option {
+	return func(r *Runner) {
+		if names != nil {
+			r.allowedEnv = names
+		}
+	}
+}
+
+// WithEnv sets variables for every command, such as credentials the command needs.
+func WithEnv(env map[string]string) Option {
+	return func(r *Runner) {
+		if len(env) > 0 {
+			r.env = env
+		}
+	}
+}
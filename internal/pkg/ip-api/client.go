@@ -3,38 +3,104 @@ package ipapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
-	IPAPIEndpoint = "http://ip-api.com/json/"
+	IPAPIEndpoint    = "http://ip-api.com/json/"
+	IPAPIProEndpoint = "https://pro.ip-api.com/json/"
+
+	StatusSuccess = "success"
+	StatusFail    = "fail"
+
+	DefaultTimeout = 10 * time.Second
+
+	queryFields      = "status,message,continent,continentCode,country,countryCode,region,regionName,city,district,zip,lat,lon,timezone,offset,currency,isp,org,as,asname,reverse,mobile,proxy,hosting,query"
+	maxErrorBodySize = 256
 )
 
+var (
+	ErrQueryFailed = errors.New("ip-api query failed")
+	ErrRateLimited = errors.New("ip-api rate limit reached")
+)
+
+// QueryError is returned when ip-api answers with a fail status, such as for a private or reserved address.
+type QueryError struct {
+	Message string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrQueryFailed, e.Message)
+}
+
+func (e *QueryError) Is(target error) bool {
+	return target == ErrQueryFailed
+}
+
+// RateLimitError is returned once the requests allowed by ip-api in the current window are used up. No requests are
+// sent until RetryAfter has passed.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrRateLimited, e.RetryAfter)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
 type Client interface {
 	GetPublicIP(ctx context.Context) (*IPQueryResponse, error)
 	QueryIPAddress(ctx context.Context, ip string) (*IPQueryResponse, error)
 }
 
+// IPAPIClient queries ip-api.com. The free endpoint only allows a limited number of requests per minute, which ip-api
+// advertises in the X-Rl and X-Ttl headers; once none remain the client refuses requests until the window resets
+// rather than risk its address being banned. With an API key the Pro endpoint is used over HTTPS instead.
 type IPAPIClient struct {
 	httpClient *http.Client
 	endpoint   string
+	apiKey     string
+	timeout    time.Duration
+
+	mu           sync.Mutex
+	blockedUntil time.Time
 }
 
 func New(opts ...Option) *IPAPIClient {
 	retriever := &IPAPIClient{
-		httpClient: http.DefaultClient,
-		endpoint:   IPAPIEndpoint,
+		endpoint: IPAPIEndpoint,
+		timeout:  DefaultTimeout,
 	}
 
 	for _, opt := range opts {
 		opt(retriever)
 	}
 
+	if retriever.httpClient == nil {
+		retriever.httpClient = &http.Client{Timeout: retriever.timeout}
+	}
+
+	if retriever.apiKey != "" && retriever.endpoint == IPAPIEndpoint {
+		retriever.endpoint = IPAPIProEndpoint
+	}
+
 	return retriever
 }
 
 type IPQueryResponse struct {
 	Status        string  `json:"status"`
+	Message       string  `json:"message"`
 	Continent     string  `json:"continent"`
 	ContinentCode string  `json:"continentCode"`
 	Country       string  `json:"country"`
@@ -65,27 +131,93 @@ func (r *IPAPIClient) GetPublicIP(ctx context.Context) (*IPQueryResponse, error)
 }
 
 func (r *IPAPIClient) QueryIPAddress(ctx context.Context, ip string) (*IPQueryResponse, error) {
-	return r.queryIP(ctx, r.endpoint+ip)
+	return r.queryIP(ctx, r.endpoint+url.PathEscape(ip))
+}
+
+// redact removes the API key from the url included in errors of the http client, so it is not logged or sent to
+// notifiers.
+func (r *IPAPIClient) redact(err error) error {
+	var urlErr *url.Error
+	if r.apiKey != "" && errors.As(err, &urlErr) {
+		urlErr.URL = strings.ReplaceAll(urlErr.URL, url.QueryEscape(r.apiKey), "<redacted>")
+	}
+	return err
 }
 
 func (r *IPAPIClient) queryIP(ctx context.Context, endpoint string) (*IPQueryResponse, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?fields=status,message,continent,continentCode,country,countryCode,region,regionName,city,district,zip,lat,lon,timezone,offset,currency,isp,org,as,asname,reverse,mobile,proxy,hosting,query", nil)
-	if err != nil {
+	if err := r.checkRateLimit(); err != nil {
 		return nil, err
 	}
 
+	query := "?fields=" + queryFields
+	if r.apiKey != "" {
+		query += "&key=" + url.QueryEscape(r.apiKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+query, nil)
+	if err != nil {
+		return nil, r.redact(err)
+	}
+
 	response, err := r.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, r.redact(err)
 	}
 
 	defer response.Body.Close()
 
+	if response.StatusCode == http.StatusTooManyRequests {
+		retryAfter := rateLimitReset(response.Header)
+		r.blockFor(retryAfter)
+		return nil, &RateLimitError{RetryAfter: retryAfter}
+	}
+
+	// the request that uses up the window still succeeds, so stop before the next one is refused
+	if remaining, err := strconv.Atoi(response.Header.Get("X-Rl")); err == nil && remaining <= 0 {
+		r.blockFor(rateLimitReset(response.Header))
+	}
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodySize))
+		return nil, fmt.Errorf("ip-api returned status code %d: %s", response.StatusCode, body)
+	}
+
 	var ipQueryResponse IPQueryResponse
 	err = json.NewDecoder(response.Body).Decode(&ipQueryResponse)
 	if err != nil {
 		return nil, err
 	}
 
+	if ipQueryResponse.Status != StatusSuccess {
+		return nil, &QueryError{Message: ipQueryResponse.Message}
+	}
+
 	return &ipQueryResponse, nil
 }
+
+// checkRateLimit returns a RateLimitError while the rate limit advertised by ip-api has been reached.
+func (r *IPAPIClient) checkRateLimit() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if wait := time.Until(r.blockedUntil); wait > 0 {
+		return &RateLimitError{RetryAfter: wait.Round(time.Second)}
+	}
+	return nil
+}
+
+func (r *IPAPIClient) blockFor(wait time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.blockedUntil = time.Now().Add(wait)
+}
+
+// rateLimitReset returns how long until the rate limit window resets from the X-Ttl header, in seconds.
+func rateLimitReset(header http.Header) time.Duration {
+	ttl, err := strconv.Atoi(header.Get("X-Ttl"))
+	if err != nil || ttl <= 0 {
+		// ip-api windows are a minute long
+		ttl = 60
+	}
+	return time.Duration(ttl) * time.Second
+}
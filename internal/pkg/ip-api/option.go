@@ -1,12 +1,27 @@
 package ipapi
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type Option func(*IPAPIClient)
 
+// WithHTTPClient replaces the default client, which times out after DefaultTimeout.
 func WithHTTPClient(client *http.Client) Option {
 	return func(r *IPAPIClient) {
-		r.httpClient = client
+		if client != nil {
+			r.httpClient = client
+		}
+	}
+}
+
+// WithTimeout sets the timeout of the default client.
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *IPAPIClient) {
+		if timeout > 0 {
+			r.timeout = timeout
+		}
 	}
 }
 
@@ -15,3 +30,13 @@ func WithEndpoint(endpoint string) Option {
 		r.endpoint = endpoint
 	}
 }
+
+// WithAPIKey authenticates with an ip-api Pro key, switching to the Pro endpoint over HTTPS unless another endpoint
+// is set.
+func WithAPIKey(key string) Option {
+	return func(r *IPAPIClient) {
+		if key != "" {
+			r.apiKey = key
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotFound = errors.New("path not found")
+)
+
+// Lookup returns the value at path in a decoded JSON document. Paths are dot separated keys with optional array
+// indexes, such as "result.addresses[0].ip". A leading "$." is ignored.
+func Lookup(document any, path string) (any, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return document, nil
+	}
+
+	current := document
+	for _, segment := range strings.Split(path, ".") {
+		key, indexes, err := parseSegment(segment)
+		if err != nil {
+			return nil, err
+		}
+
+		if key != "" {
+			object, ok := current.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s is not an object", ErrNotFound, key)
+			}
+			current, ok = object[key]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+			}
+		}
+
+		for _, index := range indexes {
+			array, ok := current.([]any)
+			if !ok || index >= len(array) {
+				return nil, fmt.Errorf("%w: %s[%d]", ErrNotFound, key, index)
+			}
+			current = array[index]
+		}
+	}
+
+	return current, nil
+}
+
+// LookupString decodes data and returns the value at path formatted as a string.
+func LookupString(data []byte, path string) (string, error) {
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return "", err
+	}
+
+	value, err := Lookup(document, path)
+	if err != nil {
+		return "", err
+	}
+
+	return Format(value), nil
+}
+
+// Format renders a decoded JSON value as a string, with null as the empty string.
+func Format(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		encoded, _ := json.Marshal(value)
+		return string(encoded)
+	}
+}
+
+func parseSegment(segment string) (string, []int, error) {
+	key, rest, _ := strings.Cut(segment, "[")
+	if rest == "" {
+		return key, nil, nil
+	}
+
+	var indexes []int
+	for _, part := range strings.Split("["+rest, "[")[1:] {
+		value, ok := strings.CutSuffix(part, "]")
+		if !ok {
+			return "", nil, fmt.Errorf("invalid path segment: %s", segment)
+		}
+		index, err := strconv.Atoi(value)
+		if err != nil || index < 0 {
+			return "", nil, fmt.Errorf("invalid index in path segment: %s", segment)
+		}
+		indexes = append(indexes, index)
+	}
+
+	return key, indexes, nil
+}
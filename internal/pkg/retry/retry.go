@@ -0,0 +1,44 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxRetries is how many times a rate limited request is sent again before giving up.
+	MaxRetries = 3
+	// MaxRetryAfter is the longest wait for a rate limit to clear. Waiting any longer would hold up the next check of
+	// the address, so the request is dropped instead.
+	MaxRetryAfter = 30 * time.Second
+	// DefaultRetryAfter is used when a rate limited response does not say how long to wait.
+	DefaultRetryAfter = time.Second
+)
+
+// OnRateLimit calls send until it succeeds or fails without asking to wait. When send returns an error along with how
+// long to wait, it is called again once that has passed, up to MaxRetries times and as long as the wait is no longer
+// than MaxRetryAfter. name identifies what is rate limited in logs.
+func OnRateLimit(ctx context.Context, name string, send func() (time.Duration, error)) error {
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := send()
+		if err == nil || retryAfter == 0 {
+			return err
+		}
+		if attempt == MaxRetries {
+			return fmt.Errorf("%w, gave up after %d retries", err, MaxRetries)
+		}
+		if retryAfter > MaxRetryAfter {
+			return fmt.Errorf("%w, not waiting %s", err, retryAfter)
+		}
+
+		log.Warn().Dur("retry_after", retryAfter).Msgf("%s rate limited, retrying", name)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryAfter):
+		}
+	}
+}
@@ -3,17 +3,47 @@ package gateway
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
 )
 
 var (
 	ErrRecordNotFound       = errors.New("record not found")
 	ErrMultipleRecordsFound = errors.New("multiple records found")
+	// ErrRecordReadNotSupported is returned by GetRecordIpAddress for providers that can only be written to.
+	ErrRecordReadNotSupported = errors.New("reading the record is not supported by the provider")
 )
 
+// BackoffError is returned by DNS updaters when the provider asks for updates to stop for a while, such as after a
+// rate limit, a server outage or an abuse block. The record is not published to again until RetryAfter has passed.
+type BackoffError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *BackoffError) Error() string {
+	return fmt.Sprintf("%s, backing off for %s", e.Err, e.RetryAfter)
+}
+
+func (e *BackoffError) Unwrap() error {
+	return e.Err
+}
+
+// DNSUpdater publishes addresses to a single DNS record. UpdateRecordIpAddress is given previousIP, the address last
+// published to the record or nil when unknown, for providers whose update requests include it.
 type DNSUpdater interface {
 	RecordName() string
-	GetRecordIpAddress(ctx context.Context) (net.IP, error)
+	GetRecordIpAddress(ctx context.Context, family inet.Family) (net.IP, error)
 	CreateRecordWithIpAddress(ctx context.Context, ip net.IP) error
-	UpdateRecordIpAddress(ctx context.Context, ip net.IP) error
+	UpdateRecordIpAddress(ctx context.Context, ip, previousIP net.IP) error
+}
+
+// DuplicateRecordResolver is implemented by DNS updaters that can act on every record sharing their name, for when
+// a lookup returns ErrMultipleRecordsFound.
+type DuplicateRecordResolver interface {
+	UpdateAllRecordsIpAddress(ctx context.Context, ip net.IP) error
+	DeleteDuplicateRecords(ctx context.Context, family inet.Family) error
 }
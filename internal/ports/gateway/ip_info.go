@@ -0,0 +1,13 @@
+package gateway
+
+import (
+	"context"
+	"net"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+)
+
+// IPInfoProvider looks up information about a public address, such as the network announcing it.
+type IPInfoProvider interface {
+	GetIPInfo(ctx context.Context, ip net.IP) (inet.IPInfo, error)
+}
@@ -2,9 +2,53 @@ package gateway
 
 import (
 	"context"
+	"errors"
 	"net"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/event"
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
+)
+
+var (
+	ErrAddressFamilyNotSupported = errors.New("address family not supported")
 )
 
 type IPRetriever interface {
 	GetPublicIPv4(context.Context) (net.IP, error)
+	GetPublicIPv6(context.Context) (net.IP, error)
+}
+
+// Retrieval is an address along with what the IP retriever learned while retrieving it. NATMapping is set by
+// retrievers able to detect how the address is translated. Warnings are problems that did not prevent an address from
+// being returned, and may be set even when retrieval failed.
+type Retrieval struct {
+	IP         net.IP
+	NATMapping inet.NATMapping
+	Warnings   []event.Event
+}
+
+// DetailedIPRetriever is implemented by IP retrievers that learn more than the address, such as the NAT mapping
+// detected with STUN or a consensus of sources that disagree.
+type DetailedIPRetriever interface {
+	RetrievePublicIP(ctx context.Context, family inet.Family) (Retrieval, error)
+}
+
+// RetrievePublicIP retrieves the address of family from retriever, along with what it learned when it is a
+// DetailedIPRetriever.
+func RetrievePublicIP(ctx context.Context, retriever IPRetriever, family inet.Family) (Retrieval, error) {
+	if detailed, ok := retriever.(DetailedIPRetriever); ok {
+		return detailed.RetrievePublicIP(ctx, family)
+	}
+
+	var ip net.IP
+	var err error
+	switch family {
+	case inet.IPv4:
+		ip, err = retriever.GetPublicIPv4(ctx)
+	case inet.IPv6:
+		ip, err = retriever.GetPublicIPv6(ctx)
+	default:
+		err = ErrAddressFamilyNotSupported
+	}
+	return Retrieval{IP: ip}, err
 }
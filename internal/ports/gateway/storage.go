@@ -3,9 +3,33 @@ package gateway
 import (
 	"context"
 	"net"
+	"time"
+
+	"github.com/awlsring/dynamic-ip-watcher/internal/core/domain/inet"
 )
 
+// RecordID identifies a DNS record in storage, so records of the same name at different providers or in different
+// zones keep their own state.
+type RecordID struct {
+	Provider string
+	Zone     string
+	Name     string
+}
+
 type Storage interface {
-	SaveIPAddress(ctx context.Context, ip net.IP) error
-	GetLastKnownIPAddress(ctx context.Context) (net.IP, error)
+	// SaveIPAddress and GetLastKnownIPAddress hold the last public address observed for the family.
+	SaveIPAddress(ctx context.Context, family inet.Family, ip net.IP) error
+	GetLastKnownIPAddress(ctx context.Context, family inet.Family) (net.IP, error)
+	// GetIPAddressObservedAt returns when the last known address was first observed, or the zero time when unknown.
+	GetIPAddressObservedAt(ctx context.Context, family inet.Family) (time.Time, error)
+	// SavePublishedIPAddress and GetPublishedIPAddress hold the last address the DNS provider confirmed for a record.
+	// GetPublishedIPAddress returns nil when nothing has been published.
+	SavePublishedIPAddress(ctx context.Context, record RecordID, family inet.Family, ip net.IP) error
+	GetPublishedIPAddress(ctx context.Context, record RecordID, family inet.Family) (net.IP, error)
+	// GetLastReconciledAt returns the zero time when the record has never been reconciled.
+	GetLastReconciledAt(ctx context.Context, record RecordID, family inet.Family) (time.Time, error)
+	SaveLastReconciledAt(ctx context.Context, record RecordID, family inet.Family, at time.Time) error
+	// GetBackoffUntil returns the time before which the record must not be published to, or the zero time.
+	GetBackoffUntil(ctx context.Context, record RecordID, family inet.Family) (time.Time, error)
+	SaveBackoffUntil(ctx context.Context, record RecordID, family inet.Family, until time.Time) error
 }
@@ -7,7 +7,12 @@ overlay: {
   cfg = config.services.dynamic-ip-watcher;
   format = pkgs.formats.json {};
   filterNulls = lib.filterAttrsRecursive (v: v != null);
-  configFile = format.generate "dynamic-ip-watcher.json" cfg;
+  configFile = format.generate "dynamic-ip-watcher.json" (cfg
+    // {
+      watcher = {
+        inherit (cfg) daemon interval jitter timeout;
+      };
+    });
 in {
   imports = [./options.nix];
   
@@ -21,7 +26,10 @@ in {
       wants = ["network-online.target"];
 
       serviceConfig = {
-        Type = "oneshot";
+        Type =
+          if cfg.daemon
+          then "simple"
+          else "oneshot";
         ExecStart = "${pkgs.dynamic-ip-watcher}/bin/dynamic-ip-watcher --config-path ${configFile}";
         User = "dynamic-ip-watcher";
         Group = "dynamic-ip-watcher";
@@ -31,7 +39,7 @@ in {
       };
     };
 
-    systemd.timers.dynamic-ip-watcher = {
+    systemd.timers.dynamic-ip-watcher = lib.mkIf (!cfg.daemon) {
       description = "Timer for Dynamic IP Watcher Service";
       wantedBy = ["timers.target"];
 
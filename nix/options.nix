@@ -3,7 +3,332 @@
   pkgs,
   lib,
   ...
-}: {
+}: let
+  dnsRecordSubmodule = with lib;
+  with types;
+    submodule {
+      options = {
+        type = mkOption {
+          type = enum ["cloudflare" "route53" "rfc2136" "http" "dyndns2" "exec" "none"];
+          default = "none";
+          description = "Type of DNS provider.";
+        };
+        apiKey = mkOption {
+          type = str;
+          default = "";
+          description = "API key for the DNS provider. Used with 'cloudflare' type.";
+        };
+        zoneName = mkOption {
+          type = str;
+          default = "";
+          description = "Zone name for the DNS provider.";
+        };
+        recordName = mkOption {
+          type = str;
+          default = "";
+          description = "Record name for the DNS provider.";
+        };
+        addressFamily = mkOption {
+          type = enum ["ipv4" "ipv6" "both"];
+          default = "ipv4";
+          description = "Which address families to publish. 'ipv4' manages an A record, 'ipv6' an AAAA record, 'both' manages both. 'ipv6' and 'both' need an IP source that can return IPv6 addresses.";
+        };
+        hostedZoneId = mkOption {
+          type = str;
+          default = "";
+          description = "Hosted zone ID, looked up from zoneName when empty. Used with 'route53' type.";
+        };
+        accessKeyId = mkOption {
+          type = str;
+          default = "";
+          description = "AWS access key ID, or a path to a file containing it. The default AWS credential chain is used when empty. Used with 'route53' type.";
+        };
+        secretAccessKey = mkOption {
+          type = str;
+          default = "";
+          description = "AWS secret access key, or a path to a file containing it. Used with 'route53' type.";
+        };
+        region = mkOption {
+          type = str;
+          default = "";
+          description = "AWS region, defaults to us-east-1. Used with 'route53' type.";
+        };
+        endpoint = mkOption {
+          type = str;
+          default = "";
+          description = "Override of the provider API endpoint. Used with 'route53' type.";
+        };
+        ttl = mkOption {
+          type = int;
+          default = 0;
+          description = "TTL of written records, defaults to 300. Used with 'route53' and 'rfc2136' types.";
+        };
+        changeTimeout = mkOption {
+          type = str;
+          default = "";
+          description = "How long to wait for a change to be in sync, at most 20s. Changes are not waited for when empty. Used with 'route53' type.";
+        };
+        server = mkOption {
+          type = str;
+          default = "";
+          description = "Server to send updates to. For 'rfc2136' the authoritative server as host or host:port, for 'dyndns2' the provider's base URL such as https://dynupdate.no-ip.com. Used with 'rfc2136' and 'dyndns2' types.";
+        };
+        transport = mkOption {
+          type = enum ["udp" "tcp"];
+          default = "udp";
+          description = "Transport used to reach the server. Used with 'rfc2136' type.";
+        };
+        tsigKeyName = mkOption {
+          type = str;
+          default = "";
+          description = "Name of the TSIG key updates are signed with. Used with 'rfc2136' type.";
+        };
+        tsigSecret = mkOption {
+          type = str;
+          default = "";
+          description = "Base64 TSIG secret, or a path to a file containing it. Used with 'rfc2136' type.";
+        };
+        tsigAlgorithm = mkOption {
+          type = str;
+          default = "hmac-sha256";
+          description = "TSIG algorithm, one of hmac-sha1, hmac-sha224, hmac-sha256, hmac-sha384 or hmac-sha512. Used with 'rfc2136' type.";
+        };
+        timeout = mkOption {
+          type = str;
+          default = "";
+          description = "Request timeout, defaults to 10s for each DNS message with 'rfc2136', 10s for 'http' and 'dyndns2' and 30s for 'exec'. Used with 'rfc2136', 'http', 'dyndns2' and 'exec' types.";
+        };
+        method = mkOption {
+          type = str;
+          default = "GET";
+          description = "HTTP method of the update request. Used with 'http' type.";
+        };
+        url = mkOption {
+          type = str;
+          default = "";
+          description = "URL template of the update request, given {{.IP}}, {{.PreviousIP}}, {{.RecordName}}, {{.RecordType}} and {{.Family}}. Used with 'http' type.";
+        };
+        headers = mkOption {
+          type = attrsOf str;
+          default = {};
+          description = "Header templates of the update request. Used with 'http' type.";
+        };
+        body = mkOption {
+          type = str;
+          default = "";
+          description = "Body template of the update request. Used with 'http' type.";
+        };
+        successStatusCodes = mkOption {
+          type = listOf int;
+          default = [];
+          description = "Status codes that mean the update succeeded, defaults to any 2xx. Used with 'http' type.";
+        };
+        successBodyRegex = mkOption {
+          type = str;
+          default = "";
+          description = "Regex the response body must match for the update to succeed. Used with 'http' type.";
+        };
+        successJsonPath = mkOption {
+          type = str;
+          default = "";
+          description = "JSON path in the response, such as 'result.success', that must be truthy or equal successJsonValue. Used with 'http' type.";
+        };
+        successJsonValue = mkOption {
+          type = str;
+          default = "";
+          description = "Value expected at successJsonPath. Used with 'http' type.";
+        };
+        username = mkOption {
+          type = str;
+          default = "";
+          description = "Account username. Used with 'dyndns2' type.";
+        };
+        password = mkOption {
+          type = str;
+          default = "";
+          description = "Account password, or a path to a file containing it. Used with 'dyndns2' type.";
+        };
+        userAgent = mkOption {
+          type = str;
+          default = "";
+          description = "User agent sent to the provider, in the form company-device/version. Used with 'dyndns2' type.";
+        };
+        command = mkOption {
+          type = listOf str;
+          default = [];
+          description = "Command and arguments run to publish the address, templated with {{.IP}}, {{.RecordName}} and similar. Used with 'exec' type.";
+        };
+        env = mkOption {
+          type = attrsOf str;
+          default = {};
+          description = "Environment variables set for the command. Used with 'exec' type.";
+        };
+        allowedEnv = mkOption {
+          type = nullOr (listOf str);
+          default = null;
+          description = "Environment variables passed through to the command, defaulting to PATH, HOME, LANG, LC_ALL and TZ. Used with 'exec' type.";
+        };
+        createIfMissing = mkOption {
+          type = bool;
+          default = false;
+          description = "Create the record when it does not exist instead of failing.";
+        };
+        duplicatePolicy = mkOption {
+          type = enum ["fail" "update-all" "delete-duplicates"];
+          default = "fail";
+          description = "What to do when more than one record with the name exists.";
+        };
+      };
+    };
+  ipRetrieverOptions = with lib;
+  with types; {
+    type = mkOption {
+      type = enum ["ipapi" "ipify" "icanhazip" "dns" "stun" "gateway" "interface" "http" "exec"];
+      default = "ipapi";
+      description = "Service used to look up the public address.";
+    };
+    name = mkOption {
+      type = str;
+      default = "";
+      description = "Name of the source in logs and notifications, defaults to the type.";
+    };
+    timeout = mkOption {
+      type = str;
+      default = "";
+      description = "Timeout of each lookup (e.g., '10s'), defaults to 10s for sources queried over HTTP.";
+    };
+    apiKey = mkOption {
+      type = str;
+      default = "";
+      description = "ip-api Pro key, or a path to a file containing it. Uses the Pro endpoint over HTTPS when set. Used with 'ipapi' type.";
+    };
+    provider = mkOption {
+      type = enum ["opendns" "cloudflare" "google"];
+      default = "opendns";
+      description = "Resolver that reports the address queries come from. Used with 'dns' type.";
+    };
+    ipv4Servers = mkOption {
+      type = listOf str;
+      default = [];
+      description = "Resolvers queried for the IPv4 address, as host or host:port, overriding the provider's. Used with 'dns' type.";
+    };
+    ipv6Servers = mkOption {
+      type = listOf str;
+      default = [];
+      description = "Resolvers queried for the IPv6 address, as host or host:port, overriding the provider's. Used with 'dns' type.";
+    };
+    queryName = mkOption {
+      type = str;
+      default = "";
+      description = "Name queried, overriding the provider's (e.g., 'myip.opendns.com'). Used with 'dns' type.";
+    };
+    queryType = mkOption {
+      type = enum ["" "address" "TXT"];
+      default = "";
+      description = "Record type queried, 'address' for A or AAAA, overriding the provider's. Used with 'dns' type.";
+    };
+    queryClass = mkOption {
+      type = str;
+      default = "";
+      description = "Class queried, such as 'IN' or 'CH', overriding the provider's. Used with 'dns' type.";
+    };
+    servers = mkOption {
+      type = listOf str;
+      default = [];
+      description = "STUN servers, as host or host:port, defaulting to public servers. Used with 'stun' type.";
+    };
+    disableNatDetection = mkOption {
+      type = bool;
+      default = false;
+      description = "Skip the extra requests made to detect how the NAT maps ports. Used with 'stun' type.";
+    };
+    methods = mkOption {
+      type = listOf (enum ["upnp" "natpmp" "pcp"]);
+      default = [];
+      description = "Protocols used to ask the router for its WAN address, in order. Defaults to all of them. Used with 'gateway' type.";
+    };
+    gatewayAddress = mkOption {
+      type = str;
+      default = "";
+      description = "Router queried with NAT-PMP and PCP, defaulting to the default gateway. Used with 'gateway' type.";
+    };
+    ssdpAddress = mkOption {
+      type = str;
+      default = "";
+      description = "Address UPnP discovery is sent to, defaulting to the SSDP multicast group. Used with 'gateway' type.";
+    };
+    ipv4Url = mkOption {
+      type = str;
+      default = "";
+      description = "URL returning the IPv4 address, leave empty if IPv4 is not available. Used with 'http' type.";
+    };
+    ipv6Url = mkOption {
+      type = str;
+      default = "";
+      description = "URL returning the IPv6 address, leave empty if IPv6 is not available. Used with 'http' type.";
+    };
+    headers = mkOption {
+      type = attrsOf str;
+      default = {};
+      description = "Headers sent with each request. Used with 'http' type.";
+    };
+    regex = mkOption {
+      type = str;
+      default = "";
+      description = "Regex extracting the address from the response, using the first capture group if it has one. Used with 'http' type.";
+    };
+    jsonPath = mkOption {
+      type = str;
+      default = "";
+      description = "Path of the address in a JSON response (e.g., 'ip' or 'wan.address'). Used with 'http' type.";
+    };
+    command = mkOption {
+      type = listOf str;
+      default = [];
+      description = "Command and arguments run to print the address of the family in $FAMILY. Used with 'exec' type.";
+    };
+    env = mkOption {
+      type = attrsOf str;
+      default = {};
+      description = "Environment variables set for the command. Used with 'exec' type.";
+    };
+    allowedEnv = mkOption {
+      type = nullOr (listOf str);
+      default = null;
+      description = "Environment variables passed through to the command, defaulting to PATH, HOME, LANG, LC_ALL and TZ. Used with 'exec' type.";
+    };
+    interface = mkOption {
+      type = str;
+      default = "";
+      description = "Interface the address is read from, defaulting to every interface that is up. Used with 'interface' type.";
+    };
+    allowPrivate = mkOption {
+      type = bool;
+      default = false;
+      description = "Use private IPv4 and unique local IPv6 addresses, which must also be in ipValidation.allowedPrefixes to be published. Used with 'interface' type.";
+    };
+    includeTemporary = mkOption {
+      type = bool;
+      default = false;
+      description = "Use temporary IPv6 privacy addresses. Used with 'interface' type.";
+    };
+    includeDeprecated = mkOption {
+      type = bool;
+      default = false;
+      description = "Use IPv6 addresses past their preferred lifetime. Used with 'interface' type.";
+    };
+    prefixes = mkOption {
+      type = listOf str;
+      default = [];
+      description = "Only use addresses within these CIDR prefixes. Used with 'interface' type.";
+    };
+    excludePrefixes = mkOption {
+      type = listOf str;
+      default = [];
+      description = "Never use addresses within these CIDR prefixes. Used with 'interface' type.";
+    };
+  };
+in {
   options = with lib;
   with types; {
     services.dynamic-ip-watcher = {
@@ -19,30 +344,108 @@
         default = "1m";
         description = "Interval at which to run the Dynamic IP Watcher service (e.g., '1h', '30m').";
       };
+      daemon = mkOption {
+        type = types.bool;
+        default = false;
+        description = ''
+          Run Dynamic IP Watcher as a long-running service that checks on `interval` itself, instead of a oneshot service triggered by a systemd timer.
+        '';
+      };
+      jitter = mkOption {
+        type = str;
+        default = "0s";
+        description = "Maximum random delay added to each interval when running as a daemon (e.g., '30s').";
+      };
+      timeout = mkOption {
+        type = str;
+        default = "1m";
+        description = "Maximum duration of a single address check (e.g., '1m').";
+      };
       dnsRecord = mkOption {
-        description = "Options for a managed DNS Record.";
+        description = "Options for a managed DNS Record. Prefer dnsRecords, which supports managing several records.";
+        default = {};
+        type = dnsRecordSubmodule;
+      };
+      dnsRecords = mkOption {
+        description = "DNS Records to keep updated with the current address.";
+        default = [];
+        type = listOf dnsRecordSubmodule;
+      };
+      ipRetriever = mkOption {
+        description = "Source of the current public address.";
+        default = {};
+        type = submodule {
+          options =
+            ipRetrieverOptions
+            // {
+              type = mkOption {
+                type = enum ["ipapi" "ipify" "icanhazip" "dns" "stun" "gateway" "interface" "http" "exec" "consensus"];
+                default = "ipapi";
+                description = "Service used to look up the public address. 'consensus' queries several sources and uses an address once a quorum agree.";
+              };
+              quorum = mkOption {
+                type = int;
+                default = 0;
+                description = "Number of sources that must agree, defaults to a majority. Used with 'consensus' type.";
+              };
+              sources = mkOption {
+                type = listOf (submodule {options = ipRetrieverOptions;});
+                default = [];
+                description = "Sources to query, in order of preference. Used with 'consensus' type.";
+              };
+            };
+        };
+      };
+      ipValidation = mkOption {
+        description = "Options for rejecting addresses that should not be published.";
         default = {};
         type = submodule {
           options = {
-            type = mkOption {
-              type = enum ["cloudflare" "none"];
-              default = "none";
-              description = "Type of DNS provider.";
+            allowedPrefixes = mkOption {
+              type = listOf str;
+              default = [];
+              description = "Only publish addresses within these CIDR prefixes. Addresses in them are published even if they are not publicly routable.";
+            };
+            allowedAsns = mkOption {
+              type = listOf ints.unsigned;
+              default = [];
+              description = "Only publish addresses announced by these autonomous systems, looked up with ip-api.";
+            };
+          };
+        };
+      };
+      enrichment = mkOption {
+        description = "Options for adding the ISP and location of addresses to change notifications.";
+        default = {};
+        type = submodule {
+          options = {
+            enabled = mkOption {
+              type = bool;
+              default = false;
+              description = "Whether to look up the ISP, location and kind of network of each new address with ip-api.";
             };
             apiKey = mkOption {
               type = str;
               default = "";
-              description = "API key for the DNS provider.";
+              description = "ip-api Pro key, or a path to a file containing it, used for enrichment and ASN validation lookups.";
             };
-            zoneName = mkOption {
-              type = str;
-              default = "";
-              description = "Zone name for the DNS provider.";
+          };
+        };
+      };
+      reconciliation = mkOption {
+        description = "Options for checking DNS records against the current address and correcting drift.";
+        default = {};
+        type = submodule {
+          options = {
+            enabled = mkOption {
+              type = bool;
+              default = false;
+              description = "Whether to read DNS records and correct any that do not match the current address.";
             };
-            recordName = mkOption {
+            interval = mkOption {
               type = str;
-              default = "";
-              description = "Record name for the DNS provider.";
+              default = "0s";
+              description = "Minimum time between checks of a record. '0s' checks on every run.";
             };
           };
         };
@@ -66,7 +469,7 @@
         type = listOf (submodule {
           options = {
             type = mkOption {
-              type = enum ["discord"];
+              type = enum ["discord" "slack" "telegram" "email"];
               description = ''
                 The type of notifier.
               '';
@@ -75,14 +478,15 @@
               type = str;
               default = "";
               description = ''
-                The webhook url to send the message.
+                The webhook url to send the message. With 'slack' type, used when no token is set.
               '';
             };
             username = mkOption {
               type = str;
               default = "";
               description = ''
-                The username to use in the message. Used with 'discord' type.
+                The username to use in the message with 'discord' and 'slack' types. With 'email' type, the user to
+                authenticate to the SMTP server as.
               '';
             };
             avatarUrl = mkOption {
@@ -92,6 +496,145 @@
                 The avatar url to use in the message. Used with 'discord' type.
               '';
             };
+            plainText = mkOption {
+              type = bool;
+              default = false;
+              description = ''
+                Send events as plain text instead of embeds. Used with 'discord' type.
+              '';
+            };
+            token = mkOption {
+              type = str;
+              default = "";
+              description = ''
+                The bot token, or a path to a file containing it. With 'slack' type, posts with chat.postMessage
+                instead of a webhook and needs the chat:write scope. Required with 'telegram' type.
+              '';
+            };
+            channel = mkOption {
+              type = str;
+              default = "";
+              description = ''
+                The channel ID or name to post to with the bot token. Used with 'slack' type.
+              '';
+            };
+            iconUrl = mkOption {
+              type = str;
+              default = "";
+              description = ''
+                The icon url to use in the message. Used with 'slack' type.
+              '';
+            };
+            apiUrl = mkOption {
+              type = str;
+              default = "";
+              description = ''
+                The API base url, defaulting to https://slack.com/api/ with 'slack' type and
+                https://api.telegram.org with 'telegram' type.
+              '';
+            };
+            chatId = mkOption {
+              type = str;
+              default = "";
+              description = ''
+                The numeric ID of the chat, or the @username of a public channel, to send to. Used with 'telegram' type.
+              '';
+            };
+            messageThreadId = mkOption {
+              type = int;
+              default = 0;
+              description = ''
+                The topic of a forum supergroup to send to. Used with 'telegram' type.
+              '';
+            };
+            parseMode = mkOption {
+              type = enum ["HTML" "MarkdownV2"];
+              default = "HTML";
+              description = ''
+                How messages are formatted. Used with 'telegram' type.
+              '';
+            };
+            silentInfo = mkOption {
+              type = bool;
+              default = false;
+              description = ''
+                Send informational events, such as a successful change, without a notification sound. Used with
+                'telegram' type.
+              '';
+            };
+            host = mkOption {
+              type = str;
+              default = "";
+              description = ''
+                The SMTP server to send through. Used with 'email' type.
+              '';
+            };
+            port = mkOption {
+              type = int;
+              default = 0;
+              description = ''
+                The port of the SMTP server, defaulting to 587 for STARTTLS, 465 for implicit TLS and 25 otherwise.
+                Used with 'email' type.
+              '';
+            };
+            tlsMode = mkOption {
+              type = enum ["starttls" "implicit" "none"];
+              default = "starttls";
+              description = ''
+                How the connection to the SMTP server is encrypted. Used with 'email' type.
+              '';
+            };
+            insecureSkipVerify = mkOption {
+              type = bool;
+              default = false;
+              description = ''
+                Accept any certificate from the SMTP server, such as a self-signed one. Used with 'email' type.
+              '';
+            };
+            password = mkOption {
+              type = str;
+              default = "";
+              description = ''
+                The SMTP password, or a path to a file containing it. Used with 'email' type.
+              '';
+            };
+            authMechanism = mkOption {
+              type = enum ["plain" "login"];
+              default = "plain";
+              description = ''
+                The SASL mechanism used when a username is set. Used with 'email' type.
+              '';
+            };
+            from = mkOption {
+              type = str;
+              default = "";
+              description = ''
+                The sender address, optionally with a name such as "Watcher <watcher@example.com>". Used with 'email'
+                type.
+              '';
+            };
+            to = mkOption {
+              type = listOf str;
+              default = [];
+              description = ''
+                The recipient addresses. Used with 'email' type.
+              '';
+            };
+            subject = mkOption {
+              type = str;
+              default = "[dynamic-ip-watcher] {{.Title}} on {{.Host}}";
+              description = ''
+                The subject as a Go template over .Title, .Host, .RunID, .Kind and .Severity. Used with 'email' type.
+              '';
+            };
+            timeout = mkOption {
+              type = str;
+              default = "";
+              description = ''
+                Bounds the whole exchange with the SMTP server with 'email' type, defaulting to 30s, and each request
+                with 'discord', 'slack' and 'telegram' types, defaulting to 10s.
+              '';
+            };
           };
         });
       };